@@ -0,0 +1,93 @@
+package path
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// dnsLabelMaxLength is the maximum length of a single DNS label.
+const dnsLabelMaxLength = 63
+
+// dnslinkProfile validates DNSLink names under the IDNA2008 lookup rules
+// (non-transitional, so that characters like ß are preserved).
+var dnslinkProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.VerifyDNSLength(true),
+	idna.Transitional(false),
+)
+
+// isDNSLinkName returns true if the given /ipns/ name looks like a domain
+// name rather than a key. Keys (CIDs and peer IDs) never contain dots.
+func isDNSLinkName(name string) bool {
+	return strings.Contains(name, ".")
+}
+
+// trimRootLabel drops the trailing dot of a fully qualified domain name, so
+// that "example.com." and "example.com" name the same domain.
+func trimRootLabel(name string) string {
+	if trimmed := strings.TrimSuffix(name, "."); trimmed != "" {
+		return trimmed
+	}
+	return name
+}
+
+// DNSLinkNameToASCII validates a DNSLink domain name under IDNA2008 and
+// returns its canonical ASCII form, with every label lowercased and
+// internationalized labels punycode-encoded (xn--). The trailing dot of a
+// fully qualified name is dropped.
+//
+// "bücher.example", "xn--bcher-kva.example" and "Bücher.Example." all map to
+// "xn--bcher-kva.example".
+func DNSLinkNameToASCII(name string) (string, error) {
+	ascii, err := dnslinkProfile.ToASCII(trimRootLabel(name))
+	if err != nil {
+		return "", fmt.Errorf("invalid DNSLink name %q: %w", name, err)
+	}
+	return ascii, nil
+}
+
+// DNSLinkNameToUnicode validates a DNSLink domain name under IDNA2008 and
+// returns its Unicode form, decoding any punycode-encoded labels. The
+// trailing dot of a fully qualified name is dropped.
+//
+// Both "bücher.example" and "xn--bcher-kva.example" map to
+// "bücher.example".
+func DNSLinkNameToUnicode(name string) (string, error) {
+	unicode, err := dnslinkProfile.ToUnicode(trimRootLabel(name))
+	if err != nil {
+		return "", fmt.Errorf("invalid DNSLink name %q: %w", name, err)
+	}
+	return unicode, nil
+}
+
+// InlineDNSLinkName converts a DNSLink domain name to the single-label form
+// used by subdomain gateways: every "-" becomes "--" and every "." becomes
+// "-". The name is converted to its canonical ASCII form first, so
+// internationalized names are inlined from their punycode representation.
+//
+// For example, "bücher.example" becomes "xn----bcher--kva-example".
+func InlineDNSLinkName(name string) (string, error) {
+	ascii, err := DNSLinkNameToASCII(name)
+	if err != nil {
+		return "", err
+	}
+	label := strings.ReplaceAll(ascii, "-", "--")
+	label = strings.ReplaceAll(label, ".", "-")
+	if len(label) > dnsLabelMaxLength {
+		return "", fmt.Errorf("inlined DNSLink name %q is longer than %d characters", label, dnsLabelMaxLength)
+	}
+	return label, nil
+}
+
+// UninlineDNSLinkName reverses InlineDNSLinkName, returning the canonical
+// ASCII form of the DNSLink domain name encoded in the given label.
+func UninlineDNSLinkName(label string) (string, error) {
+	// "@" can't appear in a DNS label so it is safe to use as a placeholder
+	name := strings.ReplaceAll(label, "--", "@")
+	name = strings.ReplaceAll(name, "-", ".")
+	name = strings.ReplaceAll(name, "@", "-")
+	return DNSLinkNameToASCII(name)
+}
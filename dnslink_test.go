@@ -0,0 +1,101 @@
+package path
+
+import (
+	"testing"
+)
+
+func TestDNSLinkNameConversion(t *testing.T) {
+	cases := map[string][]string{
+		"bücher.example":        {"xn--bcher-kva.example", "bücher.example"},
+		"xn--bcher-kva.example": {"xn--bcher-kva.example", "bücher.example"},
+		"Bücher.EXAMPLE":        {"xn--bcher-kva.example", "bücher.example"},
+		"bücher.example.":       {"xn--bcher-kva.example", "bücher.example"},
+		"en.wikipedia.org":      {"en.wikipedia.org", "en.wikipedia.org"},
+		"faß.de":                {"xn--fa-hia.de", "faß.de"},
+	}
+
+	for name, expected := range cases {
+		ascii, err := DNSLinkNameToASCII(name)
+		if err != nil {
+			t.Fatalf("DNSLinkNameToASCII(%s) failed: %s", name, err)
+		}
+		if ascii != expected[0] {
+			t.Fatalf("expected DNSLinkNameToASCII(%s) to return %s, not %s", name, expected[0], ascii)
+		}
+		unicode, err := DNSLinkNameToUnicode(name)
+		if err != nil {
+			t.Fatalf("DNSLinkNameToUnicode(%s) failed: %s", name, err)
+		}
+		if unicode != expected[1] {
+			t.Fatalf("expected DNSLinkNameToUnicode(%s) to return %s, not %s", name, expected[1], unicode)
+		}
+	}
+}
+
+func TestInvalidDNSLinkNames(t *testing.T) {
+	for _, name := range []string{
+		"under_score.example",
+		"-leading.example",
+		"empty..label",
+		"xn--a.example",
+	} {
+		if _, err := DNSLinkNameToASCII(name); err == nil {
+			t.Errorf("expected %s to be an invalid DNSLink name", name)
+		}
+		// such names may still resolve, paths keep them as they are
+		p, err := ParsePath("/ipns/" + name)
+		if err != nil {
+			t.Errorf("ParsePath failed to parse /ipns/%s: %s", name, err)
+		} else if p.String() != "/ipns/"+name {
+			t.Errorf("expected ParsePath(/ipns/%s) to keep the name, not return %s", name, p)
+		}
+	}
+}
+
+func TestInlineDNSLinkName(t *testing.T) {
+	cases := map[string]string{
+		"en.wikipedia-on-ipfs.org": "en-wikipedia--on--ipfs-org",
+		"bücher.example":           "xn----bcher--kva-example",
+		"xn--bcher-kva.example":    "xn----bcher--kva-example",
+	}
+
+	for name, expected := range cases {
+		label, err := InlineDNSLinkName(name)
+		if err != nil {
+			t.Fatalf("InlineDNSLinkName(%s) failed: %s", name, err)
+		}
+		if label != expected {
+			t.Fatalf("expected InlineDNSLinkName(%s) to return %s, not %s", name, expected, label)
+		}
+		back, err := UninlineDNSLinkName(label)
+		if err != nil {
+			t.Fatalf("UninlineDNSLinkName(%s) failed: %s", label, err)
+		}
+		ascii, _ := DNSLinkNameToASCII(name)
+		if back != ascii {
+			t.Fatalf("expected UninlineDNSLinkName(%s) to return %s, not %s", label, ascii, back)
+		}
+	}
+}
+
+func TestParsePathCanonicalDNSLinkName(t *testing.T) {
+	cases := map[string]string{
+		"/ipns/bücher.example":                                   "/ipns/xn--bcher-kva.example",
+		"/ipns/xn--bcher-kva.example/a":                          "/ipns/xn--bcher-kva.example/a",
+		"/ipns/Bücher.Example/a/b":                               "/ipns/xn--bcher-kva.example/a/b",
+		"/ipns/example.com.":                                     "/ipns/example.com",
+		"/ipns/Example.COM./a":                                   "/ipns/example.com/a",
+		"/ipns/My_Site.example.com/a":                            "/ipns/My_Site.example.com/a",
+		"/ipns/QmdfTbBqBPQ7VNxZEYEj14VmRuZBkqFbiwReogJgS1zR1n/a": "/ipns/QmdfTbBqBPQ7VNxZEYEj14VmRuZBkqFbiwReogJgS1zR1n/a",
+	}
+
+	for p, expected := range cases {
+		parsed, err := ParsePath(p)
+		if err != nil {
+			t.Fatalf("ParsePath failed to parse \"%s\", but should have succeeded", p)
+		}
+		if parsed.String() != expected {
+			t.Fatalf("expected ParsePath(%s) to return %s, not %s", p, expected, parsed)
+		}
+	}
+}
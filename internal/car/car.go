@@ -0,0 +1,170 @@
+// Package car implements reading and writing of CARv1 streams, as described
+// in https://ipld.io/specs/transport/car/carv1/.
+//
+// github.com/ipld/go-car is not used: its releases supporting the
+// go-ipld-prime LinkSystem require a newer go-ipld-prime than this module
+// builds with, and path proofs only need to write blocks in a given order and
+// read them back.
+package car
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	dagcbor "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/fluent"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/multiformats/go-varint"
+)
+
+// maxSectionSize bounds the size of the header and of each block section
+// read from a stream.
+const maxSectionSize = 32 << 20
+
+// Writer writes a CARv1 stream.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter writes the header of a CARv1 stream with the given roots to w
+// and returns a Writer for its blocks.
+func NewWriter(w io.Writer, roots []cid.Cid) (*Writer, error) {
+	header, err := fluent.BuildMap(basicnode.Prototype.Map, 2, func(ma fluent.MapAssembler) {
+		ma.AssembleEntry("roots").CreateList(int64(len(roots)), func(la fluent.ListAssembler) {
+			for _, r := range roots {
+				la.AssembleValue().AssignLink(cidlink.Link{Cid: r})
+			}
+		})
+		ma.AssembleEntry("version").AssignInt(1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := dagcbor.Encode(header, &buf); err != nil {
+		return nil, err
+	}
+	if err := writeSection(w, buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// WriteBlock appends a block to the stream.
+func (cw *Writer) WriteBlock(blk blocks.Block) error {
+	return writeSection(cw.w, blk.Cid().Bytes(), blk.RawData())
+}
+
+func writeSection(w io.Writer, parts ...[]byte) error {
+	size := 0
+	for _, p := range parts {
+		size += len(p)
+	}
+	if _, err := w.Write(varint.ToUvarint(uint64(size))); err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reader reads a CARv1 stream.
+type Reader struct {
+	r *bufio.Reader
+	// Roots are the roots listed in the header of the stream.
+	Roots []cid.Cid
+}
+
+// NewReader reads the header of the CARv1 stream r and returns a Reader for
+// its blocks.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	data, err := readSection(br)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("invalid car header: %w", err)
+	}
+	nb := basicnode.Prototype.Map.NewBuilder()
+	if err := dagcbor.Decode(nb, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("invalid car header: %w", err)
+	}
+	roots, err := parseHeader(nb.Build())
+	if err != nil {
+		return nil, fmt.Errorf("invalid car header: %w", err)
+	}
+	return &Reader{r: br, Roots: roots}, nil
+}
+
+func parseHeader(header ipld.Node) ([]cid.Cid, error) {
+	version, err := header.LookupByString("version")
+	if err != nil {
+		return nil, err
+	}
+	if v, err := version.AsInt(); err != nil || v != 1 {
+		return nil, fmt.Errorf("unsupported version")
+	}
+	rootList, err := header.LookupByString("roots")
+	if err != nil {
+		return nil, err
+	}
+	roots := make([]cid.Cid, 0, rootList.Length())
+	for itr := rootList.ListIterator(); itr != nil && !itr.Done(); {
+		_, nd, err := itr.Next()
+		if err != nil {
+			return nil, err
+		}
+		lnk, err := nd.AsLink()
+		if err != nil {
+			return nil, err
+		}
+		clnk, ok := lnk.(cidlink.Link)
+		if !ok {
+			return nil, fmt.Errorf("root is not a cid link: %v", lnk)
+		}
+		roots = append(roots, clnk.Cid)
+	}
+	return roots, nil
+}
+
+// Next returns the next block of the stream, or io.EOF when there are no
+// more blocks. The data of the block is not checked against its cid.
+func (cr *Reader) Next() (blocks.Block, error) {
+	data, err := readSection(cr.r)
+	if err != nil {
+		return nil, err
+	}
+	n, c, err := cid.CidFromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid car block: %w", err)
+	}
+	return blocks.NewBlockWithCid(data[n:], c)
+}
+
+func readSection(r *bufio.Reader) ([]byte, error) {
+	size, err := varint.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 || size > maxSectionSize {
+		return nil, fmt.Errorf("invalid section size %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,51 @@
+package car
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	blk1 := blocks.NewBlock([]byte("one"))
+	blk2 := blocks.NewBlock([]byte("two"))
+
+	var buf bytes.Buffer
+	cw, err := NewWriter(&buf, []cid.Cid{blk1.Cid()})
+	require.NoError(t, err)
+	require.NoError(t, cw.WriteBlock(blk1))
+	require.NoError(t, cw.WriteBlock(blk2))
+
+	cr, err := NewReader(&buf)
+	require.NoError(t, err)
+	require.Equal(t, []cid.Cid{blk1.Cid()}, cr.Roots)
+	for _, want := range []blocks.Block{blk1, blk2} {
+		got, err := cr.Next()
+		require.NoError(t, err)
+		require.Equal(t, want.Cid(), got.Cid())
+		require.Equal(t, want.RawData(), got.RawData())
+	}
+	_, err = cr.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestTruncated(t *testing.T) {
+	blk := blocks.NewBlock([]byte("block"))
+
+	var buf bytes.Buffer
+	cw, err := NewWriter(&buf, []cid.Cid{blk.Cid()})
+	require.NoError(t, err)
+	require.NoError(t, cw.WriteBlock(blk))
+
+	cr, err := NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	require.NoError(t, err)
+	_, err = cr.Next()
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+
+	_, err = NewReader(bytes.NewReader(nil))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
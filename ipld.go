@@ -0,0 +1,41 @@
+package path
+
+import (
+	"fmt"
+	"strings"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+)
+
+// ToIPLDPath splits fpath into its root cid and the ipld-prime path of the
+// segments following it. Segments are parsed with ipld.ParsePathSegment, so
+// that numeric segments can address both list indexes and map keys.
+func ToIPLDPath(fpath Path) (cid.Cid, ipld.Path, error) {
+	c, segments, err := SplitAbsPath(fpath)
+	if err != nil {
+		return cid.Cid{}, ipld.Path{}, err
+	}
+	ps := make([]ipld.PathSegment, len(segments))
+	for i, s := range segments {
+		ps[i] = ipld.ParsePathSegment(s)
+	}
+	return c, ipld.NewPath(ps), nil
+}
+
+// FromIPLDPath builds the path made of the root cid followed by the segments
+// of p, after the given prefix (such as "/ipfs/" or "/ipld/"). It fails for
+// segments that cannot be written in a path: empty segments, dot segments and
+// segments containing a slash.
+func FromIPLDPath(prefix string, root cid.Cid, p ipld.Path) (Path, error) {
+	segments := make([]string, 0, p.Len()+1)
+	segments = append(segments, root.String())
+	for _, ps := range p.Segments() {
+		s := ps.String()
+		if s == "" || s == "." || s == ".." || strings.Contains(s, "/") {
+			return "", &ErrInvalidPath{error: fmt.Errorf("segment %q cannot be written in a path", s), path: p.String()}
+		}
+		segments = append(segments, s)
+	}
+	return FromSegments(prefix, segments...)
+}
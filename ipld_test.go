@@ -0,0 +1,57 @@
+package path
+
+import (
+	"testing"
+
+	"github.com/ipld/go-ipld-prime"
+)
+
+func TestIPLDPathRoundTrip(t *testing.T) {
+	p := FromString("/ipld/QmdfTbBqBPQ7VNxZEYEj14VmRuZBkqFbiwReogJgS1zR1n/items/3/name")
+	c, ip, err := ToIPLDPath(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip.Len() != 3 {
+		t.Fatalf("expected 3 segments, got %d", ip.Len())
+	}
+	if idx, err := ip.Segments()[1].Index(); err != nil || idx != 3 {
+		t.Fatalf("expected segment 1 to be index 3, got %d (%v)", idx, err)
+	}
+
+	back, err := FromIPLDPath("/ipld/", c, ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back != p {
+		t.Fatalf("expected %s, got %s", p, back)
+	}
+
+	typed := ipld.NewPath([]ipld.PathSegment{ipld.PathSegmentOfString("items"), ipld.PathSegmentOfInt(3)})
+	back, err = FromIPLDPath("/ipfs/", c, typed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.String() != "/ipfs/QmdfTbBqBPQ7VNxZEYEj14VmRuZBkqFbiwReogJgS1zR1n/items/3" {
+		t.Fatalf("unexpected path %s", back)
+	}
+}
+
+func TestFromIPLDPathInvalidSegments(t *testing.T) {
+	c, _, err := ToIPLDPath(FromString("/ipfs/QmdfTbBqBPQ7VNxZEYEj14VmRuZBkqFbiwReogJgS1zR1n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"", ".", "..", "a/b"} {
+		ip := ipld.NewPath([]ipld.PathSegment{ipld.PathSegmentOfString(s)})
+		if _, err := FromIPLDPath("/ipfs/", c, ip); err == nil {
+			t.Fatalf("expected segment %q to be rejected", s)
+		}
+	}
+}
+
+func TestToIPLDPathInvalid(t *testing.T) {
+	if _, _, err := ToIPLDPath(FromString("/ipfs/foo/bar")); err == nil {
+		t.Fatal("expected an invalid cid to be rejected")
+	}
+}
@@ -90,6 +90,9 @@ func FromSegments(prefix string, seg ...string) (Path, error) {
 // ParsePath returns a well-formed ipfs Path.
 // The returned path will always be prefixed with /ipfs/ or /ipns/.
 // The prefix will be added if not present in the given string.
+// DNSLink names in /ipns/ paths that are valid under IDNA2008 are converted
+// to their canonical ASCII form (see DNSLinkNameToASCII); other names are
+// kept as they are.
 // This function will return an error when the given string is
 // not a valid ipfs path.
 //
@@ -132,6 +135,16 @@ func ParsePath(txt string) (Path, error) {
 		if parts[2] == "" {
 			return "", &ErrInvalidPath{error: fmt.Errorf("not enough path components"), path: txt}
 		}
+		// Canonicalize DNSLink names so that equivalent Unicode and
+		// punycode spellings result in the same path. Names IDNA2008
+		// does not allow, such as names with underscores, may still
+		// resolve and are kept as they are.
+		if isDNSLinkName(parts[2]) {
+			if name, err := DNSLinkNameToASCII(parts[2]); err == nil {
+				parts[2] = name
+				return Path(strings.Join(parts, "/")), nil
+			}
+		}
 	default:
 		return "", &ErrInvalidPath{error: fmt.Errorf("unknown namespace %q", parts[1]), path: txt}
 	}
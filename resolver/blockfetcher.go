@@ -0,0 +1,121 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-blockservice"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-fetcher"
+	bsfetcher "github.com/ipfs/go-fetcher/impl/blockservice"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+)
+
+// FetcherFactory is a blockservice fetcher factory that also exposes its
+// block service to resolvers. Resolvers using it read blocks from a
+// blockservice session through link systems of their own: every block is
+// read once, bounded by the block timeout and accounted in the resolution
+// budget, including the blocks an ADL loads internally, and nodes are
+// reified for the namespace of each path.
+//
+// Its sessions, when used as a plain fetcher.Factory, are those of the
+// embedded bsfetcher.FetcherConfig.
+type FetcherFactory struct {
+	bsfetcher.FetcherConfig
+	blockService blockservice.BlockService
+}
+
+// NewFetcherFactory returns a FetcherFactory fetching blocks from bs,
+// configured as bsfetcher.NewFetcherConfig configures its factories.
+func NewFetcherFactory(bs blockservice.BlockService) FetcherFactory {
+	return FetcherFactory{
+		FetcherConfig: bsfetcher.NewFetcherConfig(bs),
+		blockService:  bs,
+	}
+}
+
+// WithReifier derives a FetcherFactory from the same block service with
+// the NodeReifier nr.
+func (f FetcherFactory) WithReifier(nr ipld.NodeReifier) fetcher.Factory {
+	f.NodeReifier = nr
+	return f
+}
+
+var _ fetcher.Factory = FetcherFactory{}
+
+// BlockGetter retrieves raw blocks. It is satisfied by a
+// blockservice.BlockService.
+type BlockGetter interface {
+	GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error)
+}
+
+// newBlockGetterFetcher returns a FetcherFactory loading blocks from bg
+// only. Every block loaded, including the blocks an ADL loads internally, is
+// reported to onBlock, if set.
+func newBlockGetterFetcher(bg BlockGetter, onBlock func(blocks.Block) error) FetcherFactory {
+	f := NewFetcherFactory(blockservice.New(getterBlockstore{getter: bg, onBlock: onBlock}, nil))
+	f.PrototypeChooser = dagpb.AddSupportToChooser(bsfetcher.DefaultPrototypeChooser)
+	return f
+}
+
+// errReadOnly is returned when writing to a getterBlockstore.
+var errReadOnly = errors.New("blockstore is read-only")
+
+// getterBlockstore is a read-only blockstore.Blockstore over a BlockGetter,
+// backing an offline blockservice.
+type getterBlockstore struct {
+	getter  BlockGetter
+	onBlock func(blocks.Block) error
+}
+
+var _ blockstore.Blockstore = getterBlockstore{}
+
+func (bs getterBlockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	blk, err := bs.getter.GetBlock(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if bs.onBlock != nil {
+		if err := bs.onBlock(blk); err != nil {
+			return nil, err
+		}
+	}
+	return blk, nil
+}
+
+func (bs getterBlockstore) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	_, err := bs.getter.GetBlock(ctx, c)
+	if errors.Is(err, blockstore.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (bs getterBlockstore) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	blk, err := bs.getter.GetBlock(ctx, c)
+	if err != nil {
+		return -1, err
+	}
+	return len(blk.RawData()), nil
+}
+
+func (getterBlockstore) DeleteBlock(context.Context, cid.Cid) error {
+	return errReadOnly
+}
+
+func (getterBlockstore) Put(context.Context, blocks.Block) error {
+	return errReadOnly
+}
+
+func (getterBlockstore) PutMany(context.Context, []blocks.Block) error {
+	return errReadOnly
+}
+
+func (getterBlockstore) AllKeysChan(context.Context) (<-chan cid.Cid, error) {
+	return nil, errReadOnly
+}
+
+func (getterBlockstore) HashOnRead(bool) {}
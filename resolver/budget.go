@@ -0,0 +1,148 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+)
+
+// BudgetLimit names one of the limits of a Budget.
+type BudgetLimit string
+
+const (
+	// BudgetBlocks limits the number of blocks fetched.
+	BudgetBlocks BudgetLimit = "blocks"
+	// BudgetBytes limits the total size of the blocks fetched.
+	BudgetBytes BudgetLimit = "bytes"
+	// BudgetDepth limits the number of path segments resolved.
+	BudgetDepth BudgetLimit = "depth"
+	// BudgetNodes limits the number of nodes visited.
+	BudgetNodes BudgetLimit = "nodes"
+)
+
+// Budget limits the resources a single resolution may use. A zero value for
+// any field means that resource is not limited.
+//
+// Blocks loaded internally by an ADL, such as the shards of a HAMT-sharded
+// directory, are counted like any other when the fetcher factory of the
+// resolver is a FetcherFactory. Other factories load them out of sight.
+type Budget struct {
+	// MaxBlocks is the maximum number of blocks fetched, including the root
+	// block.
+	MaxBlocks int
+	// MaxBytes is the maximum total size of the blocks fetched, as read.
+	// Resolutions with a byte budget fail unless the fetcher factory is a
+	// FetcherFactory.
+	MaxBytes int64
+	// MaxDepth is the maximum number of path segments resolved.
+	MaxDepth int
+	// MaxNodes is the maximum number of nodes visited, including the root
+	// node.
+	MaxNodes int
+}
+
+// ErrBudgetExceeded is returned when a resolution goes over one of the
+// limits of its Budget.
+type ErrBudgetExceeded struct {
+	Limit BudgetLimit
+	Max   int64
+	// Segment is the path segment being resolved when the limit was hit. It
+	// is empty if the limit was hit while loading the root block.
+	Segment string
+	// Index is the index of Segment among the segments following the root
+	// of the path, or -1 for the root block.
+	Index int
+}
+
+// Error implements the Error interface for ErrBudgetExceeded with a useful
+// human readable message.
+func (e ErrBudgetExceeded) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("resolution exceeded the %s budget of %d at the root", e.Limit, e.Max)
+	}
+	return fmt.Sprintf("resolution exceeded the %s budget of %d at segment %d (%q)", e.Limit, e.Max, e.Index, e.Segment)
+}
+
+type budgetKey struct{}
+
+// ContextWithBudget returns a context that limits every resolution made with
+// it to the given budget. Limits set on the resolver itself (see WithBudget)
+// still apply: the lower of the two limits wins.
+func ContextWithBudget(ctx context.Context, b Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, b)
+}
+
+func budgetFromContext(ctx context.Context) Budget {
+	b, _ := ctx.Value(budgetKey{}).(Budget)
+	return b
+}
+
+// merge returns a budget with the lowest of each limit set in b or o.
+func (b Budget) merge(o Budget) Budget {
+	return Budget{
+		MaxBlocks: minLimit(b.MaxBlocks, o.MaxBlocks),
+		MaxBytes:  minLimit64(b.MaxBytes, o.MaxBytes),
+		MaxDepth:  minLimit(b.MaxDepth, o.MaxDepth),
+		MaxNodes:  minLimit(b.MaxNodes, o.MaxNodes),
+	}
+}
+
+func minLimit(a, b int) int {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+func minLimit64(a, b int64) int64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// budgetTracker accounts the resources used by a single resolution. Limits
+// are reported as hit at the segment with the given name and index (see
+// ErrBudgetExceeded).
+type budgetTracker struct {
+	Budget
+
+	blocks int
+	bytes  int64
+	nodes  int
+}
+
+// fetchBlock accounts a block about to be fetched.
+func (t *budgetTracker) fetchBlock(segment string, index int) error {
+	t.blocks++
+	if t.MaxBlocks > 0 && t.blocks > t.MaxBlocks {
+		return ErrBudgetExceeded{Limit: BudgetBlocks, Max: int64(t.MaxBlocks), Segment: segment, Index: index}
+	}
+	return nil
+}
+
+// fetchedBytes accounts the size of a block fetched.
+func (t *budgetTracker) fetchedBytes(size int, segment string, index int) error {
+	t.bytes += int64(size)
+	if t.MaxBytes > 0 && t.bytes > t.MaxBytes {
+		return ErrBudgetExceeded{Limit: BudgetBytes, Max: t.MaxBytes, Segment: segment, Index: index}
+	}
+	return nil
+}
+
+// checkDepth verifies that resolving the segments of a path stays within the
+// depth limit.
+func (t *budgetTracker) checkDepth(segments []string) error {
+	if t.MaxDepth > 0 && len(segments) > t.MaxDepth {
+		return ErrBudgetExceeded{Limit: BudgetDepth, Max: int64(t.MaxDepth), Segment: segments[t.MaxDepth], Index: t.MaxDepth}
+	}
+	return nil
+}
+
+// visit accounts a node reached.
+func (t *budgetTracker) visit(segment string, index int) error {
+	t.nodes++
+	if t.MaxNodes > 0 && t.nodes > t.MaxNodes {
+		return ErrBudgetExceeded{Limit: BudgetNodes, Max: int64(t.MaxNodes), Segment: segment, Index: index}
+	}
+	return nil
+}
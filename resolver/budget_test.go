@@ -0,0 +1,183 @@
+package resolver_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	bsfetcher "github.com/ipfs/go-fetcher/impl/blockservice"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	merkledag "github.com/ipfs/go-merkledag"
+	dagmock "github.com/ipfs/go-merkledag/test"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/ipfs/go-unixfsnode/data"
+	"github.com/ipfs/go-unixfsnode/data/builder"
+	dagcbor "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	dagjson "github.com/ipld/go-ipld-prime/codec/dagjson"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+)
+
+// cborBlock encodes the given dag-json document as a dag-cbor block.
+func cborBlock(t *testing.T, json string) blocks.Block {
+	t.Helper()
+	nb := basicnode.Prototype.Any.NewBuilder()
+	require.NoError(t, dagjson.Decode(nb, strings.NewReader(json)))
+	out := new(bytes.Buffer)
+	require.NoError(t, dagcbor.Encode(nb.Build(), out))
+	c, err := cid.Prefix{
+		Version:  1,
+		Codec:    cid.DagCBOR,
+		MhType:   multihash.SHA2_256,
+		MhLength: 32,
+	}.Sum(out.Bytes())
+	require.NoError(t, err)
+	blk, err := blocks.NewBlockWithCid(out.Bytes(), c)
+	require.NoError(t, err)
+	return blk
+}
+
+func TestBudgets(t *testing.T) {
+	ctx := context.Background()
+	bsrv := dagmock.Bserv()
+
+	leaf := cborBlock(t, `{"v":{"w":1}}`)
+	mid := cborBlock(t, `{"next":{"/":"`+leaf.Cid().String()+`"}}`)
+	root := cborBlock(t, `{"a":{"b":{"next":{"/":"`+mid.Cid().String()+`"}}}}`)
+	for _, blk := range []blocks.Block{leaf, mid, root} {
+		require.NoError(t, bsrv.AddBlock(ctx, blk))
+	}
+	p := path.FromString(root.Cid().String() + "/a/b/next/next/v/w")
+	fetcherFactory := resolver.NewFetcherFactory(bsrv)
+
+	cases := []struct {
+		budget  resolver.Budget
+		limit   resolver.BudgetLimit
+		segment string
+		index   int
+	}{
+		{resolver.Budget{MaxBlocks: 1}, resolver.BudgetBlocks, "next", 2},
+		{resolver.Budget{MaxBlocks: 2}, resolver.BudgetBlocks, "next", 3},
+		{resolver.Budget{MaxDepth: 4}, resolver.BudgetDepth, "v", 4},
+		{resolver.Budget{MaxNodes: 3}, resolver.BudgetNodes, "next", 2},
+		{resolver.Budget{MaxBytes: int64(len(root.RawData()))}, resolver.BudgetBytes, "next", 2},
+		{resolver.Budget{MaxBytes: 1}, resolver.BudgetBytes, "", -1},
+	}
+
+	for _, c := range cases {
+		r := resolver.NewBasicResolver(fetcherFactory, resolver.WithBudget(c.budget))
+		_, _, err := r.ResolvePath(ctx, p)
+		var budgetErr resolver.ErrBudgetExceeded
+		require.True(t, errors.As(err, &budgetErr), "expected a budget error for %+v, got %v", c.budget, err)
+		require.Equal(t, c.limit, budgetErr.Limit)
+		require.Equal(t, c.segment, budgetErr.Segment)
+		require.Equal(t, c.index, budgetErr.Index)
+	}
+
+	// a budget large enough for the whole path
+	budget := resolver.Budget{MaxBlocks: 3, MaxDepth: 6, MaxNodes: 7, MaxBytes: int64(len(root.RawData()) + len(mid.RawData()) + len(leaf.RawData()))}
+	r := resolver.NewBasicResolver(fetcherFactory, resolver.WithBudget(budget))
+	nodes, err := r.ResolvePathComponents(ctx, p)
+	require.NoError(t, err)
+	require.Len(t, nodes, 7)
+
+	// per-call budgets are combined with the resolver budget
+	_, _, err = r.ResolveToLastNode(resolver.ContextWithBudget(ctx, resolver.Budget{MaxBlocks: 2}), p)
+	require.EqualError(t, err, resolver.ErrBudgetExceeded{Limit: resolver.BudgetBlocks, Max: 2, Segment: "next", Index: 3}.Error())
+	_, _, err = r.ResolveToLastNode(resolver.ContextWithBudget(ctx, resolver.Budget{MaxBlocks: 10}), p)
+	require.NoError(t, err)
+
+	// byte budgets need a FetcherFactory
+	r = resolver.NewBasicResolver(bsfetcher.NewFetcherConfig(bsrv), resolver.WithBudget(resolver.Budget{MaxBytes: 1 << 20}))
+	_, _, err = r.ResolvePath(ctx, p)
+	require.Error(t, err)
+}
+
+// hamtDir returns a UnixFS HAMT-sharded directory with a fanout of 8 holding
+// the single entry name: every bucket of its root shard links to the same
+// child shard, every bucket of which links to target.
+func hamtDir(t *testing.T, name string, target *merkledag.ProtoNode) (*merkledag.ProtoNode, *merkledag.ProtoNode) {
+	t.Helper()
+	shard := func(names func(bucket int) string, nd *merkledag.ProtoNode) *merkledag.ProtoNode {
+		ufs, err := builder.BuildUnixFS(func(b *builder.Builder) {
+			builder.DataType(b, data.Data_HAMTShard)
+			builder.HashType(b, 0x22)
+			builder.Fanout(b, 8)
+			builder.Data(b, []byte{0xff})
+		})
+		require.NoError(t, err)
+		s := new(merkledag.ProtoNode)
+		s.SetData(data.EncodeUnixFSData(ufs))
+		for i := 0; i < 8; i++ {
+			require.NoError(t, s.AddNodeLink(names(i), nd))
+		}
+		return s
+	}
+	child := shard(func(i int) string { return fmt.Sprintf("%X%s", i, name) }, target)
+	root := shard(func(i int) string { return fmt.Sprintf("%X", i) }, child)
+	return root, child
+}
+
+func TestBudgetsCountShards(t *testing.T) {
+	ctx := context.Background()
+
+	target := randNode()
+	root, child := hamtDir(t, "foo", target)
+	p := path.FromString("/ipfs/" + root.Cid().String() + "/foo")
+	factory := unixfsFetcherFactory(t, root, child, target)
+
+	// the child shard loaded by the HAMT is counted
+	r := resolver.NewBasicResolver(factory, resolver.WithBudget(resolver.Budget{MaxBlocks: 1}))
+	_, _, err := r.ResolveToLastNode(ctx, p)
+	var berr resolver.ErrBudgetExceeded
+	require.ErrorAs(t, err, &berr)
+	require.Equal(t, resolver.ErrBudgetExceeded{Limit: resolver.BudgetBlocks, Max: 1, Segment: "foo", Index: 0}, berr)
+
+	r = resolver.NewBasicResolver(factory, resolver.WithBudget(resolver.Budget{MaxBytes: int64(len(root.RawData()))}))
+	_, _, err = r.ResolveToLastNode(ctx, p)
+	require.ErrorAs(t, err, &berr)
+	require.Equal(t, resolver.ErrBudgetExceeded{Limit: resolver.BudgetBytes, Max: int64(len(root.RawData())), Segment: "foo", Index: 0}, berr)
+
+	r = resolver.NewBasicResolver(factory, resolver.WithBudget(resolver.Budget{MaxBlocks: 2, MaxBytes: int64(len(root.RawData()) + len(child.RawData()))}))
+	c, _, err := r.ResolveToLastNode(ctx, p)
+	require.NoError(t, err)
+	require.Equal(t, target.Cid(), c)
+}
+
+// countingBlockstore counts the blocks read from it.
+type countingBlockstore struct {
+	resolver.LocalBlockstore
+	gets map[cid.Cid]int
+}
+
+func (bs *countingBlockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	bs.gets[c]++
+	return bs.LocalBlockstore.Get(ctx, c)
+}
+
+func TestByteBudgetReadsBlocksOnce(t *testing.T) {
+	ctx := context.Background()
+
+	target := randNode()
+	root, child := hamtDir(t, "foo", target)
+	bs := &countingBlockstore{LocalBlockstore: blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore())), gets: make(map[cid.Cid]int)}
+	for _, n := range []*merkledag.ProtoNode{root, child, target} {
+		require.NoError(t, bs.LocalBlockstore.(blockstore.Blockstore).Put(ctx, n))
+	}
+
+	budget := resolver.Budget{MaxBytes: int64(len(root.RawData()) + len(child.RawData()) + len(target.RawData()))}
+	r := resolver.NewBasicResolver(resolver.NewOfflineFetcherFactory(bs), resolver.WithBudget(budget))
+	_, lnk, err := r.ResolvePath(ctx, path.FromString("/ipfs/"+root.Cid().String()+"/foo"))
+	require.NoError(t, err)
+	require.Equal(t, target.Cid().String(), lnk.String())
+	require.Equal(t, map[cid.Cid]int{root.Cid(): 1, child.Cid(): 1, target.Cid(): 1}, bs.gets)
+}
@@ -0,0 +1,279 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-fetcher"
+	path "github.com/ipfs/go-path"
+	"github.com/ipld/go-ipld-prime"
+)
+
+// CachingResolver is a Resolver that caches the results of
+// ResolveToLastNode for immutable paths. Both successful resolutions and
+// ErrNoLink failures are cached, keyed by the root cid and segments of the
+// path; a cached failure is returned as the resolution met it, usually as an
+// ErrPartialResolution. A cached prefix of a path also serves later lookups of longer paths,
+// which then only resolve the remaining segments. With a resolver made by
+// NewBasicResolver, every prefix resolved along a path is cached too.
+//
+// Every other method is passed to the underlying Resolver, as are the
+// methods used by the functions of this package taking a Resolver, such as
+// Resolve: they do not use the cache. The resolvers derived from a
+// CachingResolver to share a session (see SessionResolver) share its cache.
+type CachingResolver struct {
+	Resolver
+
+	cache *lru.Cache
+	// stats is shared with the resolvers derived from this one, as is the
+	// cache.
+	stats *cacheCounters
+}
+
+var (
+	_ LinkResolver    = (*CachingResolver)(nil)
+	_ SessionResolver = (*CachingResolver)(nil)
+)
+
+type cacheCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// CacheStats counts the lookups made through a CachingResolver.
+type CacheStats struct {
+	// Hits counts the lookups answered from the cache alone.
+	Hits uint64
+	// Misses counts the lookups that needed the underlying resolver,
+	// including those resumed from a cached prefix.
+	Misses uint64
+}
+
+// cacheKey identifies a resolved path. Paths in different namespaces are
+// cached apart, as they resolve differently.
+type cacheKey struct {
+	ns     string
+	root   cid.Cid
+	prefix string
+}
+
+type cacheEntry struct {
+	c    cid.Cid
+	rest []string
+	err  error
+}
+
+// NewCachingResolver wraps r with a cache holding at most size paths.
+func NewCachingResolver(r Resolver, size int) (*CachingResolver, error) {
+	cache, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &CachingResolver{Resolver: r, cache: cache, stats: new(cacheCounters)}, nil
+}
+
+// ResolveToLastNode walks the given path and returns the cid of the last
+// block referenced by the path, and the path segments to traverse from the
+// final block boundary to the final node within the block. Results are
+// served from the cache when possible.
+func (r *CachingResolver) ResolveToLastNode(ctx context.Context, fpath path.Path) (cid.Cid, []string, error) {
+	c, p, err := path.SplitAbsPath(fpath)
+	if err != nil || len(p) == 0 {
+		return r.Resolver.ResolveToLastNode(ctx, fpath)
+	}
+
+	ns := pathNamespace(fpath, nil)
+
+	// resume from the longest cached prefix of the path
+	from, start := 0, cacheEntry{c: c}
+	for i := len(p); i > 0; i-- {
+		v, ok := r.cache.Get(cacheKey{ns: ns, root: c, prefix: strings.Join(p[:i], "/")})
+		if !ok {
+			continue
+		}
+		e := v.(cacheEntry)
+		if e.err != nil {
+			// a path under a missing link is missing too
+			r.stats.hits.Add(1)
+			return cid.Cid{}, nil, e.err
+		}
+		if i == len(p) {
+			r.stats.hits.Add(1)
+			return e.c, append([]string{}, e.rest...), nil
+		}
+		from, start = i, e
+		break
+	}
+	r.stats.misses.Add(1)
+
+	if br, ok := r.Resolver.(*basicResolver); ok {
+		return r.resolvePrefixes(ctx, br, fpath, ns, c, p, from, start)
+	}
+
+	rpath := fpath
+	if from > 0 {
+		segments := append([]string{start.c.String()}, start.rest...)
+		rpath, err = path.FromSegments("/"+ns+"/", append(segments, p[from:]...)...)
+		if err != nil {
+			return cid.Cid{}, nil, err
+		}
+	}
+
+	key := cacheKey{ns: ns, root: c, prefix: strings.Join(p, "/")}
+	last, rest, err := r.Resolver.ResolveToLastNode(ctx, rpath)
+	if err != nil {
+		if errors.As(err, &ErrNoLink{}) {
+			r.cache.Add(key, cacheEntry{err: err})
+		}
+		return cid.Cid{}, nil, err
+	}
+	r.cache.Add(key, cacheEntry{c: last, rest: append([]string{}, rest...)})
+	return last, rest, nil
+}
+
+// resolvePrefixes resolves the segments p[from:] of fpath with br, from
+// start, the cached resolution of the prefix p[:from], and caches the
+// resolution of every longer prefix of the path. If br has an index (see
+// WithIndex), /ipfs/ paths resume from the longest prefix found in the cache
+// or the index, and the prefixes resolved are indexed too.
+func (r *CachingResolver) resolvePrefixes(ctx context.Context, br *basicResolver, fpath path.Path, ns string, c cid.Cid, p []string, from int, start cacheEntry) (cid.Cid, []string, error) {
+	ctx, w := br.newWalker(ctx, ns)
+	defer w.close()
+
+	// the index holds UnixFS resolutions
+	idx := br.opts.index
+	if ns != nsIPFS {
+		idx = nil
+	}
+	if idx != nil {
+		if err := w.checkDepth(p); err != nil {
+			return cid.Cid{}, nil, err
+		}
+		e, n, err := idx.lookup(ctx, c, p)
+		if err != nil {
+			log.Warnf("could not look up %s in the path index: %s", fpath, err)
+		} else if n > from {
+			from, start = n, cacheEntry{c: e.c, rest: e.rest}
+			r.cache.Add(cacheKey{ns: ns, root: c, prefix: strings.Join(p[:n], "/")}, start)
+			if n == len(p) {
+				return e.c, append([]string{}, e.rest...), nil
+			}
+		}
+	}
+
+	res, prefixes, err := br.resume(ctx, w, fpath, indexEntry{c: start.c, rest: start.rest}, p, from)
+	for i, e := range prefixes {
+		key := cacheKey{ns: ns, root: c, prefix: strings.Join(p[:from+i+1], "/")}
+		r.cache.Add(key, cacheEntry{c: e.c, rest: e.rest})
+	}
+	if idx != nil {
+		if err := idx.add(ctx, c, p, from, prefixes); err != nil {
+			log.Warnf("could not add %s to the path index: %s", fpath, err)
+		}
+	}
+	if err != nil {
+		err = resumedPartialResolution(ns, c, p, from-len(start.rest), res, err)
+		if errors.As(err, &ErrNoLink{}) {
+			key := cacheKey{ns: ns, root: c, prefix: strings.Join(p, "/")}
+			r.cache.Add(key, cacheEntry{err: err})
+		}
+		return cid.Cid{}, nil, err
+	}
+
+	last, rest, err := lastNode(fpath, res.Last(), res.Remainder)
+	if err != nil {
+		return cid.Cid{}, nil, err
+	}
+	return last, append([]string{}, rest...), nil
+}
+
+// Resolve resolves fpath with the underlying Resolver (see the Resolve
+// function).
+func (r *CachingResolver) Resolve(ctx context.Context, fpath path.Path) (*ResolveResult, error) {
+	return Resolve(ctx, r.Resolver, fpath)
+}
+
+// ResolveToImmutable resolves fpath with the underlying Resolver (see the
+// ResolveToImmutable function).
+func (r *CachingResolver) ResolveToImmutable(ctx context.Context, fpath path.Path, form ImmutableForm) (*ImmutablePath, error) {
+	return ResolveToImmutable(ctx, r.Resolver, fpath, form)
+}
+
+// ResolveSnapshot resolves the sub-paths of fpath with the underlying
+// Resolver (see the ResolveSnapshot function).
+func (r *CachingResolver) ResolveSnapshot(ctx context.Context, fpath path.Path, subPaths []string) (*Snapshot, error) {
+	return ResolveSnapshot(ctx, r.Resolver, fpath, subPaths)
+}
+
+// Watch watches the resolution of fpath with the underlying Resolver (see
+// the Watch function).
+func (r *CachingResolver) Watch(ctx context.Context, fpath path.Path, interval time.Duration) (<-chan WatchEvent, error) {
+	return Watch(ctx, r.Resolver, fpath, interval)
+}
+
+// ResolveInto resolves fpath with the underlying Resolver and decodes the
+// node it points to into ptr (see the ResolveInto function).
+func (r *CachingResolver) ResolveInto(ctx context.Context, fpath path.Path, ptr interface{}, opts ...IntoOption) error {
+	return ResolveInto(ctx, r.Resolver, fpath, ptr, opts...)
+}
+
+// ResolveEach walks fpath with the underlying Resolver (see the ResolveEach
+// function).
+func (r *CachingResolver) ResolveEach(ctx context.Context, fpath path.Path, fn func(ResolvedSegment) error) error {
+	return ResolveEach(ctx, r.Resolver, fpath, fn)
+}
+
+// ResolveLinks resolves names from ndd with the underlying Resolver (see
+// LinkResolver).
+func (r *CachingResolver) ResolveLinks(ctx context.Context, ndd ipld.Node, names []string) ([]ipld.Node, error) {
+	return ResolveLinks(ctx, r.Resolver, ndd, names)
+}
+
+// NewSession returns a CachingResolver sharing the cache of r, whose
+// underlying Resolver fetches blocks through one new session (see
+// SessionResolver).
+func (r *CachingResolver) NewSession(ctx context.Context) (Resolver, error) {
+	sr, err := NewSessionResolver(ctx, r.Resolver)
+	if err != nil {
+		return nil, err
+	}
+	return &CachingResolver{Resolver: sr, cache: r.cache, stats: r.stats}, nil
+}
+
+// WithSession returns a CachingResolver sharing the cache of r, whose
+// underlying Resolver fetches blocks through session (see SessionResolver).
+func (r *CachingResolver) WithSession(session fetcher.Fetcher) (Resolver, error) {
+	sr, ok := r.Resolver.(SessionResolver)
+	if !ok {
+		return nil, ErrUnsupported{Resolver: r.Resolver, Method: "WithSession"}
+	}
+	ws, err := sr.WithSession(session)
+	if err != nil {
+		return nil, err
+	}
+	return &CachingResolver{Resolver: ws, cache: r.cache, stats: r.stats}, nil
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (r *CachingResolver) Stats() CacheStats {
+	return CacheStats{Hits: r.stats.hits.Load(), Misses: r.stats.misses.Load()}
+}
+
+// Invalidate removes every cached path rooted at root.
+func (r *CachingResolver) Invalidate(root cid.Cid) {
+	for _, k := range r.cache.Keys() {
+		if k.(cacheKey).root.Equals(root) {
+			r.cache.Remove(k)
+		}
+	}
+}
+
+// Purge removes every cached path.
+func (r *CachingResolver) Purge() {
+	r.cache.Purge()
+}
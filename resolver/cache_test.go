@@ -0,0 +1,130 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingResolver records the paths passed to ResolveToLastNode.
+type recordingResolver struct {
+	resolver.Resolver
+	paths []string
+}
+
+func (r *recordingResolver) ResolveToLastNode(ctx context.Context, fpath path.Path) (cid.Cid, []string, error) {
+	r.paths = append(r.paths, fpath.String())
+	return r.Resolver.ResolveToLastNode(ctx, fpath)
+}
+
+func TestCachingResolver(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	d := randNode()
+	require.NoError(t, c.AddNodeLink("d", d))
+	require.NoError(t, b.AddNodeLink("c", c))
+	require.NoError(t, a.AddNodeLink("b", b))
+
+	inner := &recordingResolver{Resolver: resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b, c, d))}
+	r, err := resolver.NewCachingResolver(inner, 16)
+	require.NoError(t, err)
+
+	root := a.Cid().String()
+	resolve := func(p string) (cid.Cid, []string, error) {
+		return r.ResolveToLastNode(ctx, path.FromString("/ipfs/"+root+p))
+	}
+
+	// a first lookup goes to the underlying resolver
+	last, rest, err := resolve("/b/c")
+	require.NoError(t, err)
+	require.Equal(t, c.Cid(), last)
+	require.Empty(t, rest)
+	require.Equal(t, []string{"/ipfs/" + root + "/b/c"}, inner.paths)
+
+	// the same lookup is then served from the cache
+	last, _, err = resolve("/b/c")
+	require.NoError(t, err)
+	require.Equal(t, c.Cid(), last)
+	require.Len(t, inner.paths, 1)
+
+	// a longer path resumes from the cached prefix
+	last, _, err = resolve("/b/c/d")
+	require.NoError(t, err)
+	require.Equal(t, d.Cid(), last)
+	require.Equal(t, "/ipfs/"+c.Cid().String()+"/d", inner.paths[1])
+
+	// missing links are cached, and so are the paths below them
+	_, _, missErr := resolve("/x")
+	require.ErrorAs(t, missErr, &resolver.ErrNoLink{})
+	require.ErrorAs(t, missErr, &resolver.ErrPartialResolution{})
+	_, _, err = resolve("/x")
+	require.Equal(t, missErr, err)
+	_, _, err = resolve("/x/y")
+	require.ErrorAs(t, err, &resolver.ErrNoLink{})
+	require.Len(t, inner.paths, 3)
+
+	require.Equal(t, resolver.CacheStats{Hits: 3, Misses: 3}, r.Stats())
+
+	// invalidation drops every path under the root
+	r.Invalidate(a.Cid())
+	_, _, err = resolve("/b/c")
+	require.NoError(t, err)
+	require.Len(t, inner.paths, 4)
+
+	r.Purge()
+	_, _, err = resolve("/b/c")
+	require.NoError(t, err)
+	require.Len(t, inner.paths, 5)
+}
+
+func TestCachingResolverPrefixes(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	d := randNode()
+	require.NoError(t, c.AddNodeLink("d", d))
+	require.NoError(t, b.AddNodeLink("c", c))
+	require.NoError(t, a.AddNodeLink("b", b))
+
+	factory := &countingFactory{Factory: unixfsFetcherFactory(t, a, b, c, d)}
+	r, err := resolver.NewCachingResolver(resolver.NewBasicResolver(factory), 16)
+	require.NoError(t, err)
+
+	root := a.Cid().String()
+	resolve := func(p string) (cid.Cid, []string, error) {
+		return r.ResolveToLastNode(ctx, path.FromString("/ipfs/"+root+p))
+	}
+
+	last, _, err := resolve("/b/c/d")
+	require.NoError(t, err)
+	require.Equal(t, d.Cid(), last)
+	require.Equal(t, 1, factory.sessions)
+
+	// every prefix resolved along the way is cached
+	last, rest, err := resolve("/b")
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), last)
+	require.Empty(t, rest)
+	last, _, err = resolve("/b/c")
+	require.NoError(t, err)
+	require.Equal(t, c.Cid(), last)
+	require.Equal(t, 1, factory.sessions)
+	require.Equal(t, resolver.CacheStats{Hits: 2, Misses: 1}, r.Stats())
+
+	// a negative hit returns the error of the miss
+	_, _, missErr := resolve("/b/x")
+	require.ErrorAs(t, missErr, &resolver.ErrNoLink{})
+	require.ErrorAs(t, missErr, &resolver.ErrPartialResolution{})
+	_, _, err = resolve("/b/x")
+	require.Equal(t, missErr, err)
+	require.Equal(t, 2, factory.sessions)
+}
@@ -0,0 +1,131 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	path "github.com/ipfs/go-path"
+)
+
+// DefaultDNSLinkTTL is how long DNSLink resolutions may be cached when
+// NewDNSLinkResolver is given no TTL.
+const DefaultDNSLinkTTL = time.Minute
+
+// dnslinkPrefix starts the TXT records holding DNSLink values.
+const dnslinkPrefix = "dnslink="
+
+// LookupTXTFunc looks up the TXT records of a domain name. It has the
+// signature of net.Resolver.LookupTXT.
+type LookupTXTFunc func(ctx context.Context, name string) ([]string, error)
+
+// ErrMalformedDNSLink is returned when the DNSLink record of a domain does
+// not hold a valid path.
+type ErrMalformedDNSLink struct {
+	Name   string
+	Record string
+	Err    error
+}
+
+// Error implements the Error interface for ErrMalformedDNSLink with a useful
+// human readable message.
+func (e ErrMalformedDNSLink) Error() string {
+	return fmt.Sprintf("malformed DNSLink record %q for %s: %s", e.Record, e.Name, e.Err)
+}
+
+// Unwrap returns the error found parsing the record.
+func (e ErrMalformedDNSLink) Unwrap() error {
+	return e.Err
+}
+
+// DNSLinkResolver is a NameResolver resolving domain names with DNSLink, by
+// reading the dnslink= TXT records of _dnslink.<domain>.
+//
+// When several records hold valid values, the lexicographically smallest one
+// is used. Values pointing to other /ipns/ names are returned as is: the
+// resolver using the DNSLinkResolver follows them, up to its name recursion
+// limit.
+type DNSLinkResolver struct {
+	lookup LookupTXTFunc
+	ttl    time.Duration
+}
+
+// NewDNSLinkResolver returns a DNSLinkResolver reading TXT records with
+// lookup, or with net.DefaultResolver if lookup is nil. Resolutions may be
+// cached for ttl, or for DefaultDNSLinkTTL if ttl is zero. A negative ttl
+// disables caching.
+func NewDNSLinkResolver(lookup LookupTXTFunc, ttl time.Duration) *DNSLinkResolver {
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupTXT
+	}
+	if ttl == 0 {
+		ttl = DefaultDNSLinkTTL
+	}
+	return &DNSLinkResolver{lookup: lookup, ttl: ttl}
+}
+
+// ResolveName implements NameResolver.
+func (d *DNSLinkResolver) ResolveName(ctx context.Context, name string) (path.Path, time.Duration, error) {
+	domain, err := path.DNSLinkNameToASCII(name)
+	if err != nil {
+		return "", 0, err
+	}
+
+	records, err := d.lookup(ctx, "_dnslink."+domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return "", 0, fmt.Errorf("%w: %s has no DNSLink record", ErrNameNotFound, domain)
+		}
+		return "", 0, err
+	}
+
+	p, err := parseDNSLink(domain, records)
+	if err != nil {
+		return "", 0, err
+	}
+	if d.ttl < 0 {
+		return p, 0, nil
+	}
+	return p, d.ttl, nil
+}
+
+// parseDNSLink returns the path held by the DNSLink records of domain among
+// the given TXT records.
+func parseDNSLink(domain string, records []string) (path.Path, error) {
+	var values []string
+	for _, rec := range records {
+		if strings.HasPrefix(rec, dnslinkPrefix) {
+			values = append(values, strings.TrimSpace(strings.TrimPrefix(rec, dnslinkPrefix)))
+		}
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("%w: %s has no DNSLink record", ErrNameNotFound, domain)
+	}
+
+	// choose between conflicting values independently of the order of the
+	// records
+	sort.Strings(values)
+	var malformed error
+	for _, v := range values {
+		p, err := parseDNSLinkValue(v)
+		if err == nil {
+			return p, nil
+		}
+		if malformed == nil {
+			malformed = ErrMalformedDNSLink{Name: domain, Record: dnslinkPrefix + v, Err: err}
+		}
+	}
+	return "", malformed
+}
+
+func parseDNSLinkValue(v string) (path.Path, error) {
+	if !strings.HasPrefix(v, "/") {
+		return "", fmt.Errorf("value is not a path")
+	}
+	return path.ParsePath(v)
+}
@@ -0,0 +1,82 @@
+package resolver_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/require"
+)
+
+// mapLookup is a LookupTXTFunc serving the records in a map.
+func mapLookup(records map[string][]string) resolver.LookupTXTFunc {
+	return func(_ context.Context, name string) ([]string, error) {
+		txt, ok := records[name]
+		if !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+		}
+		return txt, nil
+	}
+}
+
+func TestDNSLinkResolver(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("b", b))
+	aPath := "/ipfs/" + a.Cid().String()
+	bPath := "/ipfs/" + b.Cid().String()
+	// conflicting values resolve to the smallest one
+	first := aPath
+	if bPath < aPath {
+		first = bPath
+	}
+
+	dr := resolver.NewDNSLinkResolver(mapLookup(map[string][]string{
+		"_dnslink.example.com":           {"v=spf1 -all", "dnslink=" + aPath},
+		"_dnslink.alias.example.com":     {"dnslink=/ipns/example.com"},
+		"_dnslink.conflict.example":      {"dnslink=" + bPath, "dnslink=" + aPath},
+		"_dnslink.malformed.example":     {"dnslink=/ipfs/notacid"},
+		"_dnslink.partly.example":        {"dnslink=ipfs", "dnslink=" + bPath},
+		"_dnslink.xn--bcher-kva.example": {"dnslink=" + bPath},
+		"_dnslink.empty.example":         {"v=spf1 -all"},
+	}), 0)
+
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{"example.com", aPath},
+		{"alias.example.com", "/ipns/example.com"},
+		{"conflict.example", first},
+		{"partly.example", bPath},
+		{"bücher.example", bPath},
+	}
+	for _, tc := range testCases {
+		p, ttl, err := dr.ResolveName(ctx, tc.name)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, path.FromString(tc.want), p, tc.name)
+		require.Equal(t, resolver.DefaultDNSLinkTTL, ttl)
+	}
+
+	_, _, err := dr.ResolveName(ctx, "malformed.example")
+	var malformed resolver.ErrMalformedDNSLink
+	require.ErrorAs(t, err, &malformed)
+	require.Equal(t, "malformed.example", malformed.Name)
+	require.Equal(t, "dnslink=/ipfs/notacid", malformed.Record)
+
+	_, _, err = dr.ResolveName(ctx, "empty.example")
+	require.ErrorIs(t, err, resolver.ErrNameNotFound)
+	_, _, err = dr.ResolveName(ctx, "missing.example")
+	require.ErrorIs(t, err, resolver.ErrNameNotFound)
+
+	// the resolver follows chains of DNSLink names
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b), resolver.WithNameResolver(dr))
+	res, err := resolver.Resolve(ctx, r, path.FromString("/ipns/alias.example.com/b"))
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), res.Last().Block)
+	require.Len(t, res.Names, 2)
+}
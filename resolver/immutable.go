@@ -0,0 +1,101 @@
+package resolver
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/internal"
+)
+
+// ImmutableForm selects the form of the path returned by
+// ResolveToImmutable.
+type ImmutableForm int
+
+const (
+	// KeepSegments keeps the segments following the root of the path, so
+	// that no block needs to be fetched.
+	KeepSegments ImmutableForm = iota
+	// CollapseSegments resolves the segments following the root of the path
+	// into the cid of the deepest block they reach, keeping only the
+	// segments within that block, as ResolveToLastNode does.
+	CollapseSegments
+)
+
+// ImmutablePath is an /ipfs/ path equivalent to a possibly mutable path.
+type ImmutablePath struct {
+	// Path is the immutable /ipfs/ path.
+	Path path.Path
+	// Names holds the names resolved, in order, to find the root of Path.
+	Names []NameHop
+	// TTL is how long the mapping to Path remains valid: the lowest TTL of
+	// the names resolved. It is meaningless if the path had no names, as
+	// the mapping of an immutable path never expires (see Permanent).
+	TTL time.Duration
+}
+
+// Permanent returns true if the mapping never expires, that is if the path
+// was immutable already.
+func (ip *ImmutablePath) Permanent() bool {
+	return len(ip.Names) == 0
+}
+
+// ResolveToImmutable returns the /ipfs/ path equivalent to fpath, resolving
+// the names at the root of /ipns/ paths with r. The segments following the
+// root are kept or collapsed according to form.
+//
+// r must have a method ResolveToImmutable(ctx, fpath, form), as the
+// resolvers of this package do.
+func ResolveToImmutable(ctx context.Context, r Resolver, fpath path.Path, form ImmutableForm) (*ImmutablePath, error) {
+	ir, ok := r.(interface {
+		ResolveToImmutable(context.Context, path.Path, ImmutableForm) (*ImmutablePath, error)
+	})
+	if !ok {
+		return nil, ErrUnsupported{Resolver: r, Method: "ResolveToImmutable"}
+	}
+	return ir.ResolveToImmutable(ctx, fpath, form)
+}
+
+// ResolveToImmutable returns the /ipfs/ path equivalent to fpath (see the
+// ResolveToImmutable function).
+func (r *basicResolver) ResolveToImmutable(ctx context.Context, fpath path.Path, form ImmutableForm) (*ImmutablePath, error) {
+	ctx, span := internal.StartSpan(ctx, "basicResolver.ResolveToImmutable", trace.WithAttributes(attribute.Stringer("Path", fpath)))
+	defer span.End()
+
+	// validate path
+	if err := fpath.IsValid(); err != nil {
+		return nil, err
+	}
+
+	// only the names are resolved within this scope: ResolveToLastNode
+	// applies its own
+	sctx, cancel, blockTimeout := r.opts.withTimeout(ctx)
+	c, p, hops, err := r.splitPath(sctx, fpath, blockTimeout)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ImmutablePath{Names: hops, TTL: minTTL(hops)}
+
+	prefix := "/" + pathNamespace(fpath, hops) + "/"
+	immutable, err := path.FromSegments(prefix, append([]string{c.String()}, p...)...)
+	if err != nil {
+		return nil, err
+	}
+	if form == CollapseSegments && len(p) > 0 {
+		c, p, err = r.ResolveToLastNode(ctx, immutable)
+		if err != nil {
+			return nil, err
+		}
+		immutable, err = path.FromSegments(prefix, append([]string{c.String()}, p...)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res.Path = immutable
+	return res, nil
+}
@@ -0,0 +1,58 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveToImmutable(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	require.NoError(t, b.AddNodeLink("c", c))
+	require.NoError(t, a.AddNodeLink("b", b))
+	aPath := "/ipfs/" + a.Cid().String()
+
+	names := resolver.NewMemoryNameResolver()
+	names.Publish("site", path.FromString(aPath), time.Hour)
+	names.Publish("alias", path.FromString("/ipns/site"), time.Minute)
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b, c), resolver.WithNameResolver(names))
+
+	res, err := resolver.ResolveToImmutable(ctx, r, path.FromString("/ipns/alias/b/c"), resolver.KeepSegments)
+	require.NoError(t, err)
+	require.Equal(t, path.FromString(aPath+"/b/c"), res.Path)
+	require.Equal(t, time.Minute, res.TTL)
+	require.Len(t, res.Names, 2)
+	require.False(t, res.Permanent())
+
+	res, err = resolver.ResolveToImmutable(ctx, r, path.FromString("/ipns/site/b/c"), resolver.CollapseSegments)
+	require.NoError(t, err)
+	require.Equal(t, path.FromCid(c.Cid()), res.Path)
+	// the name was cached by the previous resolution
+	require.InDelta(t, float64(time.Hour), float64(res.TTL), float64(time.Second))
+
+	res, err = resolver.ResolveToImmutable(ctx, r, path.FromString(aPath+"/b"), resolver.CollapseSegments)
+	require.NoError(t, err)
+	require.Equal(t, path.FromCid(b.Cid()), res.Path)
+	require.True(t, res.Permanent())
+
+	_, err = resolver.ResolveToImmutable(ctx, r, path.FromString(aPath+"/missing"), resolver.CollapseSegments)
+	require.ErrorAs(t, err, &resolver.ErrNoLink{})
+
+	// caching resolvers pass it to the resolver they wrap
+	cr, err := resolver.NewCachingResolver(r, 16)
+	require.NoError(t, err)
+	res, err = resolver.ResolveToImmutable(ctx, cr, path.FromString("/ipns/site/b"), resolver.KeepSegments)
+	require.NoError(t, err)
+	require.Equal(t, path.FromString(aPath+"/b"), res.Path)
+
+	_, err = resolver.ResolveToImmutable(ctx, &recordingResolver{Resolver: r}, path.FromString(aPath), resolver.KeepSegments)
+	require.ErrorAs(t, err, &resolver.ErrUnsupported{})
+}
@@ -0,0 +1,331 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/ipld/go-ipld-prime"
+	dagcbor "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/fluent"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+)
+
+// indexVersion versions the key schema and the encoding of index entries.
+// Entries written under another version are dropped by Compact.
+const indexVersion = "v1"
+
+// indexPrefix is the datastore namespace holding every version of the index.
+var indexPrefix = datastore.NewKey("/path-index")
+
+// Index is a persistent index of resolved /ipfs/ path prefixes, kept in a
+// datastore. A resolver configured with an index (see WithIndex) consults it
+// before fetching any block and fills it with every prefix it resolves, in
+// ResolveToLastNode only: the other methods return the nodes along the path,
+// which the index does not hold. Since /ipfs/ paths are immutable, entries
+// never become stale.
+//
+// Entries are stored under /path-index/v1/<root cid>/<prefix>, where prefix
+// is the base64url encoding of the path segments after the root. When the
+// index holds more than its maximum number of entries, the oldest entries are
+// evicted.
+type Index struct {
+	ds         datastore.Batching
+	maxEntries int
+
+	mu    sync.Mutex
+	count int
+}
+
+// indexEntry is the resolution of a path prefix, as returned by
+// ResolveToLastNode.
+type indexEntry struct {
+	c     cid.Cid
+	rest  []string
+	added int64
+}
+
+// NewIndex opens the index kept in ds, bounded to maxEntries entries. A
+// maxEntries of zero means the index is not bounded.
+func NewIndex(ctx context.Context, ds datastore.Batching, maxEntries int) (*Index, error) {
+	if maxEntries < 0 {
+		return nil, fmt.Errorf("invalid maximum number of index entries: %d", maxEntries)
+	}
+	idx := &Index{ds: ds, maxEntries: maxEntries}
+	count, err := idx.countEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idx.count = count
+	return idx, nil
+}
+
+func (idx *Index) versionPrefix() datastore.Key {
+	return indexPrefix.ChildString(indexVersion)
+}
+
+func (idx *Index) key(root cid.Cid, segments []string) datastore.Key {
+	prefix := base64.RawURLEncoding.EncodeToString([]byte(strings.Join(segments, "/")))
+	return idx.versionPrefix().ChildString(root.String()).ChildString(prefix)
+}
+
+// lookup returns the entry for the longest indexed prefix of the path made of
+// root and segments, along with the number of segments in that prefix. It
+// returns 0 if no prefix is indexed.
+func (idx *Index) lookup(ctx context.Context, root cid.Cid, segments []string) (indexEntry, int, error) {
+	for n := len(segments); n > 0; n-- {
+		data, err := idx.ds.Get(ctx, idx.key(root, segments[:n]))
+		if errors.Is(err, datastore.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return indexEntry{}, 0, err
+		}
+		e, err := decodeIndexEntry(data)
+		if err != nil {
+			return indexEntry{}, 0, err
+		}
+		return e, n, nil
+	}
+	return indexEntry{}, 0, nil
+}
+
+// add indexes the resolution of each prefix of the path made of root and
+// segments with more than from segments; prefixes[i] is the resolution of
+// segments[:from+i+1]. Prefixes already indexed, such as those a concurrent
+// resolution of the same path added, are left as they are.
+func (idx *Index) add(ctx context.Context, root cid.Cid, segments []string, from int, prefixes []indexEntry) error {
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	// only the entries added are counted
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	b, err := idx.ds.Batch(ctx)
+	if err != nil {
+		return err
+	}
+	added := time.Now().UnixNano()
+	count := 0
+	for i, e := range prefixes {
+		key := idx.key(root, segments[:from+i+1])
+		has, err := idx.ds.Has(ctx, key)
+		if err != nil {
+			return err
+		}
+		if has {
+			continue
+		}
+		e.added = added
+		data, err := encodeIndexEntry(e)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(ctx, key, data); err != nil {
+			return err
+		}
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+	if err := b.Commit(ctx); err != nil {
+		return err
+	}
+
+	idx.count += count
+	if idx.maxEntries > 0 && idx.count > idx.maxEntries {
+		// evict some slack along with the excess, so that eviction does not
+		// run on every addition
+		return idx.evict(ctx, idx.maxEntries-idx.maxEntries/10)
+	}
+	return nil
+}
+
+// Compact drops the entries written under other versions of the index and
+// the entries that cannot be decoded, recounts the entries and evicts the
+// oldest ones beyond the maximum size of the index.
+func (idx *Index) Compact(ctx context.Context) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	res, err := idx.ds.Query(ctx, query.Query{Prefix: indexPrefix.String()})
+	if err != nil {
+		return err
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		return err
+	}
+
+	b, err := idx.ds.Batch(ctx)
+	if err != nil {
+		return err
+	}
+	current := idx.versionPrefix()
+	count := 0
+	for _, qe := range entries {
+		k := datastore.NewKey(qe.Key)
+		if k.IsDescendantOf(current) {
+			if _, err := decodeIndexEntry(qe.Value); err == nil {
+				count++
+				continue
+			}
+		}
+		if err := b.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+	if err := b.Commit(ctx); err != nil {
+		return err
+	}
+
+	idx.count = count
+	if idx.maxEntries > 0 && idx.count > idx.maxEntries {
+		return idx.evict(ctx, idx.maxEntries)
+	}
+	return nil
+}
+
+// evict deletes the oldest entries until at most keep remain. idx.mu must be
+// held.
+func (idx *Index) evict(ctx context.Context, keep int) error {
+	res, err := idx.ds.Query(ctx, query.Query{Prefix: idx.versionPrefix().String()})
+	if err != nil {
+		return err
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		return err
+	}
+
+	type aged struct {
+		key   string
+		added int64
+	}
+	ages := make([]aged, 0, len(entries))
+	for _, qe := range entries {
+		// undecodable entries are evicted first
+		e, _ := decodeIndexEntry(qe.Value)
+		ages = append(ages, aged{key: qe.Key, added: e.added})
+	}
+	if len(ages) <= keep {
+		idx.count = len(ages)
+		return nil
+	}
+	sort.Slice(ages, func(i, j int) bool { return ages[i].added < ages[j].added })
+
+	b, err := idx.ds.Batch(ctx)
+	if err != nil {
+		return err
+	}
+	for _, a := range ages[:len(ages)-keep] {
+		if err := b.Delete(ctx, datastore.NewKey(a.key)); err != nil {
+			return err
+		}
+	}
+	if err := b.Commit(ctx); err != nil {
+		return err
+	}
+	idx.count = keep
+	return nil
+}
+
+func (idx *Index) countEntries(ctx context.Context) (int, error) {
+	res, err := idx.ds.Query(ctx, query.Query{Prefix: idx.versionPrefix().String(), KeysOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+func encodeIndexEntry(e indexEntry) ([]byte, error) {
+	nd, err := fluent.BuildMap(basicnode.Prototype.Map, 3, func(ma fluent.MapAssembler) {
+		ma.AssembleEntry("Cid").AssignLink(cidlink.Link{Cid: e.c})
+		ma.AssembleEntry("Remainder").CreateList(int64(len(e.rest)), func(la fluent.ListAssembler) {
+			for _, s := range e.rest {
+				la.AssembleValue().AssignString(s)
+			}
+		})
+		ma.AssembleEntry("Added").AssignInt(e.added)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := dagcbor.Encode(nd, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeIndexEntry(data []byte) (indexEntry, error) {
+	nb := basicnode.Prototype.Map.NewBuilder()
+	if err := dagcbor.Decode(nb, bytes.NewReader(data)); err != nil {
+		return indexEntry{}, fmt.Errorf("invalid index entry: %w", err)
+	}
+	e, err := indexEntryFromNode(nb.Build())
+	if err != nil {
+		return indexEntry{}, fmt.Errorf("invalid index entry: %w", err)
+	}
+	return e, nil
+}
+
+func indexEntryFromNode(nd ipld.Node) (indexEntry, error) {
+	var e indexEntry
+
+	c, err := nd.LookupByString("Cid")
+	if err != nil {
+		return e, err
+	}
+	lnk, err := c.AsLink()
+	if err != nil {
+		return e, err
+	}
+	clnk, ok := lnk.(cidlink.Link)
+	if !ok {
+		return e, fmt.Errorf("not a cid link: %v", lnk)
+	}
+	e.c = clnk.Cid
+
+	rest, err := nd.LookupByString("Remainder")
+	if err != nil {
+		return e, err
+	}
+	e.rest = make([]string, 0, rest.Length())
+	for itr := rest.ListIterator(); itr != nil && !itr.Done(); {
+		_, s, err := itr.Next()
+		if err != nil {
+			return e, err
+		}
+		str, err := s.AsString()
+		if err != nil {
+			return e, err
+		}
+		e.rest = append(e.rest, str)
+	}
+
+	added, err := nd.LookupByString("Added")
+	if err != nil {
+		return e, err
+	}
+	if e.added, err = added.AsInt(); err != nil {
+		return e, err
+	}
+	return e, nil
+}
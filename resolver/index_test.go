@@ -0,0 +1,194 @@
+package resolver_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	datastore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	dssync "github.com/ipfs/go-datastore/sync"
+	merkledag "github.com/ipfs/go-merkledag"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/require"
+)
+
+func countKeys(t *testing.T, ds datastore.Datastore, prefix string) int {
+	t.Helper()
+	res, err := ds.Query(context.Background(), query.Query{Prefix: prefix, KeysOnly: true})
+	require.NoError(t, err)
+	entries, err := res.Rest()
+	require.NoError(t, err)
+	return len(entries)
+}
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	d := randNode()
+	require.NoError(t, c.AddNodeLink("d", d))
+	require.NoError(t, b.AddNodeLink("c", c))
+	require.NoError(t, a.AddNodeLink("b", b))
+
+	idx, err := resolver.NewIndex(ctx, ds, 0)
+	require.NoError(t, err)
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b, c, d), resolver.WithIndex(idx))
+
+	p, err := path.FromSegments("/ipfs/", a.Cid().String(), "b", "c")
+	require.NoError(t, err)
+	last, rest, err := r.ResolveToLastNode(ctx, p)
+	require.NoError(t, err)
+	require.Equal(t, c.Cid(), last)
+	require.Empty(t, rest)
+	require.Equal(t, 2, countKeys(t, ds, "/path-index/v1"))
+
+	// a resolver reopening the index resolves indexed paths without
+	// fetching any block
+	idx, err = resolver.NewIndex(ctx, ds, 0)
+	require.NoError(t, err)
+	r = resolver.NewBasicResolver(unixfsFetcherFactory(t), resolver.WithIndex(idx))
+	last, _, err = r.ResolveToLastNode(ctx, p)
+	require.NoError(t, err)
+	require.Equal(t, c.Cid(), last)
+
+	// and resumes longer paths from their indexed prefix
+	r = resolver.NewBasicResolver(unixfsFetcherFactory(t, c, d), resolver.WithIndex(idx))
+	last, _, err = r.ResolveToLastNode(ctx, path.FromString(p.String()+"/d"))
+	require.NoError(t, err)
+	require.Equal(t, d.Cid(), last)
+	require.Equal(t, 3, countKeys(t, ds, "/path-index/v1"))
+
+	// compaction drops other versions and broken entries, and evicts the
+	// oldest entries beyond the bound
+	require.NoError(t, ds.Put(ctx, datastore.NewKey("/path-index/v0/x"), []byte("old")))
+	require.NoError(t, ds.Put(ctx, datastore.NewKey("/path-index/v1/x/y"), []byte("broken")))
+	idx, err = resolver.NewIndex(ctx, ds, 2)
+	require.NoError(t, err)
+	require.NoError(t, idx.Compact(ctx))
+	require.Equal(t, 2, countKeys(t, ds, "/path-index"))
+
+	r = resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b, c, d), resolver.WithIndex(idx))
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString(p.String()+"/d"))
+	require.NoError(t, err)
+
+	// a resolution resumed from the index fails with the prefix of the path
+	// it was resolving
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString(p.String()+"/d/missing"))
+	var perr resolver.ErrPartialResolution
+	require.ErrorAs(t, err, &perr)
+	require.Equal(t, path.FromString(p.String()+"/d"), perr.Prefix)
+	require.Equal(t, 3, perr.Index)
+	require.Equal(t, d.Cid(), perr.Cid)
+}
+
+func TestIndexEviction(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	// a chain of 13 nodes, linked from the root down
+	nodes := []*merkledag.ProtoNode{randNode()}
+	var segments []string
+	for i := 0; i < 12; i++ {
+		n := randNode()
+		require.NoError(t, n.AddNodeLink("next", nodes[len(nodes)-1]))
+		nodes = append(nodes, n)
+		segments = append(segments, "next")
+	}
+	segments = append([]string{nodes[len(nodes)-1].Cid().String()}, segments...)
+
+	idx, err := resolver.NewIndex(ctx, ds, 10)
+	require.NoError(t, err)
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, nodes...), resolver.WithIndex(idx))
+
+	p, err := path.FromSegments("/ipfs/", segments...)
+	require.NoError(t, err)
+	_, _, err = r.ResolveToLastNode(ctx, p)
+	require.NoError(t, err)
+	require.Equal(t, 9, countKeys(t, ds, "/path-index/v1"))
+}
+
+func TestCachingResolverIndex(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	require.NoError(t, b.AddNodeLink("c", c))
+	require.NoError(t, a.AddNodeLink("b", b))
+
+	idx, err := resolver.NewIndex(ctx, ds, 0)
+	require.NoError(t, err)
+	cr, err := resolver.NewCachingResolver(resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b, c), resolver.WithIndex(idx)), 16)
+	require.NoError(t, err)
+
+	// the caching resolver fills the index of the resolver it wraps
+	p, err := path.FromSegments("/ipfs/", a.Cid().String(), "b", "c")
+	require.NoError(t, err)
+	last, _, err := cr.ResolveToLastNode(ctx, p)
+	require.NoError(t, err)
+	require.Equal(t, c.Cid(), last)
+	require.Equal(t, 2, countKeys(t, ds, "/path-index/v1"))
+
+	// and resolves from it paths missing from its cache
+	cr, err = resolver.NewCachingResolver(resolver.NewBasicResolver(unixfsFetcherFactory(t), resolver.WithIndex(idx)), 16)
+	require.NoError(t, err)
+	last, _, err = cr.ResolveToLastNode(ctx, p)
+	require.NoError(t, err)
+	require.Equal(t, c.Cid(), last)
+	last, _, err = cr.ResolveToLastNode(ctx, p)
+	require.NoError(t, err)
+	require.Equal(t, c.Cid(), last)
+	require.Equal(t, resolver.CacheStats{Hits: 1, Misses: 1}, cr.Stats())
+}
+
+func TestIndexConcurrentAdds(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	// two chains of 6 nodes, linked from the root down
+	chain := func() ([]*merkledag.ProtoNode, path.Path) {
+		nodes := []*merkledag.ProtoNode{randNode()}
+		segments := []string{}
+		for i := 0; i < 5; i++ {
+			n := randNode()
+			require.NoError(t, n.AddNodeLink("next", nodes[len(nodes)-1]))
+			nodes = append(nodes, n)
+			segments = append(segments, "next")
+		}
+		p, err := path.FromSegments("/ipfs/", append([]string{nodes[len(nodes)-1].Cid().String()}, segments...)...)
+		require.NoError(t, err)
+		return nodes, p
+	}
+	aNodes, a := chain()
+	bNodes, b := chain()
+
+	idx, err := resolver.NewIndex(ctx, ds, 10)
+	require.NoError(t, err)
+	factory := slowFactory{Factory: unixfsFetcherFactory(t, append(aNodes, bNodes...)...), delay: 20 * time.Millisecond}
+	r := resolver.NewBasicResolver(factory, resolver.WithIndex(idx))
+
+	// concurrent resolutions of a path add its prefixes once
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := r.ResolveToLastNode(ctx, a)
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 5, countKeys(t, ds, "/path-index/v1"))
+
+	// so that the index fills up to its bound without evicting anything
+	_, _, err = r.ResolveToLastNode(ctx, b)
+	require.NoError(t, err)
+	require.Equal(t, 10, countKeys(t, ds, "/path-index/v1"))
+}
@@ -0,0 +1,75 @@
+package resolver_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	bsfetcher "github.com/ipfs/go-fetcher/impl/blockservice"
+	dagmock "github.com/ipfs/go-merkledag/test"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+)
+
+// inlineCborCid returns an identity cid holding the dag-cbor encoding of json.
+func inlineCborCid(t *testing.T, json string) cid.Cid {
+	t.Helper()
+	nb := basicnode.Prototype.Any.NewBuilder()
+	require.NoError(t, dagjson.Decode(nb, strings.NewReader(json)))
+	out := new(bytes.Buffer)
+	require.NoError(t, dagcbor.Encode(nb.Build(), out))
+	c, err := cid.Prefix{
+		Version:  1,
+		Codec:    cid.DagCBOR,
+		MhType:   multihash.IDENTITY,
+		MhLength: -1,
+	}.Sum(out.Bytes())
+	require.NoError(t, err)
+	return c
+}
+
+func TestResolveInline(t *testing.T) {
+	ctx := context.Background()
+	bsrv := dagmock.Bserv()
+
+	leaf := cborBlock(t, `{"name":"leaf"}`)
+	require.NoError(t, bsrv.AddBlock(ctx, leaf))
+	inner := inlineCborCid(t, `{"leaf":{"/":"`+leaf.Cid().String()+`"},"value":1}`)
+	root := inlineCborCid(t, `{"inner":{"/":"`+inner.String()+`"}}`)
+
+	factory := &countingFactory{Factory: bsfetcher.NewFetcherConfig(bsrv)}
+	r := resolver.NewBasicResolver(factory)
+
+	// nothing is fetched for paths within identity cids
+	res, err := resolver.Resolve(ctx, r, path.FromString("/ipld/"+root.String()+"/inner/value"))
+	require.NoError(t, err)
+	require.True(t, res.Root.Inline)
+	require.Len(t, res.Segments, 2)
+	require.True(t, res.Segments[0].Inline)
+	require.False(t, res.Last().Inline)
+	require.Equal(t, inner, res.Last().Block)
+	require.Equal(t, 0, factory.sessions)
+
+	// links out of identity cids are fetched
+	res, err = resolver.Resolve(ctx, r, path.FromString("/ipld/"+root.String()+"/inner/leaf/name"))
+	require.NoError(t, err)
+	require.True(t, res.Segments[0].Inline)
+	require.False(t, res.Segments[1].Inline)
+	require.Equal(t, leaf.Cid(), res.Last().Block)
+	require.Equal(t, 1, factory.sessions)
+
+	// identity links are crossed from fetched blocks
+	outer := cborBlock(t, `{"inner":{"/":"`+inner.String()+`"}}`)
+	require.NoError(t, bsrv.AddBlock(ctx, outer))
+	last, rest, err := r.ResolveToLastNode(ctx, path.FromString("/ipld/"+outer.Cid().String()+"/inner/value"))
+	require.NoError(t, err)
+	require.Equal(t, inner, last)
+	require.Equal(t, []string{"value"}, rest)
+}
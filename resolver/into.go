@@ -0,0 +1,114 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/internal"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/node/bindnode"
+	"github.com/ipld/go-ipld-prime/schema"
+)
+
+// IntoOption configures a single call to ResolveInto.
+type IntoOption func(*intoOptions)
+
+type intoOptions struct {
+	rootType schema.Type
+}
+
+// WithRootType loads the root block of the path as a node of the schema type
+// t, so that a block not matching the schema, or a segment naming a field the
+// schema does not have, fails while resolving. When the path ends within the
+// root block, the target node is decoded with its schema type.
+func WithRootType(t schema.Type) IntoOption {
+	return func(o *intoOptions) {
+		o.rootType = t
+	}
+}
+
+// ResolveInto resolves fpath with r and decodes the node it points to into
+// the Go value ptr points to, with bindnode. ptr must be a non-nil pointer.
+//
+// Unless the path ends within a root block loaded with a schema type (see
+// WithRootType), the schema of the target is inferred from the Go type of
+// ptr.
+//
+// r must have a method ResolveInto(ctx, fpath, ptr, opts...), as the
+// resolvers of this package do.
+func ResolveInto(ctx context.Context, r Resolver, fpath path.Path, ptr interface{}, opts ...IntoOption) error {
+	ir, ok := r.(interface {
+		ResolveInto(context.Context, path.Path, interface{}, ...IntoOption) error
+	})
+	if !ok {
+		return ErrUnsupported{Resolver: r, Method: "ResolveInto"}
+	}
+	return ir.ResolveInto(ctx, fpath, ptr, opts...)
+}
+
+// ResolveInto resolves fpath and decodes the node it points to into the Go
+// value ptr points to (see the ResolveInto function).
+func (r *basicResolver) ResolveInto(ctx context.Context, fpath path.Path, ptr interface{}, opts ...IntoOption) error {
+	var o intoOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, span := internal.StartSpan(ctx, "basicResolver.ResolveInto", trace.WithAttributes(attribute.Stringer("Path", fpath)))
+	defer span.End()
+
+	if v := reflect.ValueOf(ptr); v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("cannot decode into %T: not a non-nil pointer", ptr)
+	}
+
+	// validate path
+	if err := fpath.IsValid(); err != nil {
+		return err
+	}
+
+	ctx, w := r.newWalker(ctx, "")
+	defer w.close()
+	c, p, _, err := r.resolveRoot(ctx, w, fpath)
+	if err != nil {
+		return err
+	}
+	if o.rootType != nil {
+		w.rootPrototype = bindnode.Prototype(nil, o.rootType).Representation()
+	}
+
+	res, err := r.resolve(ctx, w, c, p, true)
+	if err != nil {
+		return err
+	}
+	if err := decodeInto(res.Last().Node, ptr); err != nil {
+		return fmt.Errorf("could not decode %s into %T: %w", fpath, ptr, err)
+	}
+	return nil
+}
+
+// decodeInto copies nd into the Go value ptr points to.
+func decodeInto(nd ipld.Node, ptr interface{}) (err error) {
+	// bindnode panics on Go types it cannot bind to the schema type
+	defer func() {
+		if rerr := recover(); rerr != nil {
+			err = fmt.Errorf("%v", rerr)
+		}
+	}()
+
+	var nb ipld.NodeBuilder
+	if tn, ok := nd.(schema.TypedNode); ok {
+		nb = bindnode.Prototype(ptr, tn.Type()).NewBuilder()
+	} else {
+		nb = bindnode.Prototype(ptr, nil).Representation().NewBuilder()
+	}
+	if err := nb.AssignNode(nd); err != nil {
+		return err
+	}
+	reflect.ValueOf(ptr).Elem().Set(reflect.ValueOf(bindnode.Unwrap(nb.Build())).Elem())
+	return nil
+}
@@ -0,0 +1,112 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	bsfetcher "github.com/ipfs/go-fetcher/impl/blockservice"
+	dagmock "github.com/ipfs/go-merkledag/test"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/schema"
+	"github.com/stretchr/testify/require"
+)
+
+type testSettings struct {
+	Port  int64
+	Hosts []string
+}
+
+func configSchema() schema.Type {
+	ts := schema.TypeSystem{}
+	ts.Init()
+	ts.Accumulate(schema.SpawnString("String"))
+	ts.Accumulate(schema.SpawnInt("Int"))
+	ts.Accumulate(schema.SpawnList("List_String", "String", false))
+	ts.Accumulate(schema.SpawnStruct("Settings",
+		[]schema.StructField{
+			schema.SpawnStructField("Port", "Int", false, false),
+			schema.SpawnStructField("Hosts", "List_String", false, false),
+		},
+		schema.SpawnStructRepresentationMap(nil),
+	))
+	ts.Accumulate(schema.SpawnStruct("Config",
+		[]schema.StructField{
+			schema.SpawnStructField("Name", "String", false, false),
+			schema.SpawnStructField("Settings", "Settings", false, false),
+		},
+		schema.SpawnStructRepresentationMap(nil),
+	))
+	return ts.TypeByName("Config")
+}
+
+func TestResolveInto(t *testing.T) {
+	ctx := context.Background()
+	bsrv := dagmock.Bserv()
+
+	settings := cborBlock(t, `{"Port":8080,"Hosts":["a","b"]}`)
+	root := cborBlock(t, `{"Name":"svc","Settings":{"Port":80,"Hosts":["c"]},"Next":{"/":"`+settings.Cid().String()+`"}}`)
+	for _, blk := range []blocks.Block{settings, root} {
+		require.NoError(t, bsrv.AddBlock(ctx, blk))
+	}
+	r := resolver.NewBasicResolver(bsfetcher.NewFetcherConfig(bsrv))
+	rootPath := "/ipld/" + root.Cid().String()
+
+	var s testSettings
+	require.NoError(t, resolver.ResolveInto(ctx, r, path.FromString(rootPath+"/Settings"), &s))
+	require.Equal(t, testSettings{Port: 80, Hosts: []string{"c"}}, s)
+
+	// across a link
+	s = testSettings{}
+	require.NoError(t, resolver.ResolveInto(ctx, r, path.FromString(rootPath+"/Next"), &s))
+	require.Equal(t, testSettings{Port: 8080, Hosts: []string{"a", "b"}}, s)
+
+	var name string
+	require.NoError(t, resolver.ResolveInto(ctx, r, path.FromString(rootPath+"/Name"), &name))
+	require.Equal(t, "svc", name)
+
+	err := resolver.ResolveInto(ctx, r, path.FromString(rootPath+"/Settings"), s)
+	require.Error(t, err)
+
+	var port int64
+	err = resolver.ResolveInto(ctx, r, path.FromString(rootPath+"/Name"), &port)
+	require.Error(t, err)
+}
+
+func TestResolveIntoSchema(t *testing.T) {
+	ctx := context.Background()
+	bsrv := dagmock.Bserv()
+
+	good := cborBlock(t, `{"Name":"svc","Settings":{"Port":80,"Hosts":["c"]}}`)
+	bad := cborBlock(t, `{"Name":"svc","Settings":{"Port":"eighty","Hosts":["c"]}}`)
+	for _, blk := range []blocks.Block{good, bad} {
+		require.NoError(t, bsrv.AddBlock(ctx, blk))
+	}
+	r := resolver.NewBasicResolver(bsfetcher.NewFetcherConfig(bsrv))
+	typ := resolver.WithRootType(configSchema())
+
+	var s testSettings
+	require.NoError(t, resolver.ResolveInto(ctx, r, path.FromString("/ipld/"+good.Cid().String()+"/Settings"), &s, typ))
+	require.Equal(t, testSettings{Port: 80, Hosts: []string{"c"}}, s)
+
+	var port int64
+	require.NoError(t, resolver.ResolveInto(ctx, r, path.FromString("/ipld/"+good.Cid().String()+"/Settings/Port"), &port, typ))
+	require.Equal(t, int64(80), port)
+
+	// caching resolvers pass it, options included, to the resolver they wrap
+	cr, err := resolver.NewCachingResolver(r, 16)
+	require.NoError(t, err)
+	port = 0
+	require.NoError(t, resolver.ResolveInto(ctx, cr, path.FromString("/ipld/"+good.Cid().String()+"/Settings/Port"), &port, typ))
+	require.Equal(t, int64(80), port)
+
+	// fields the schema does not have fail while resolving
+	err = resolver.ResolveInto(ctx, r, path.FromString("/ipld/"+good.Cid().String()+"/Settings/Timeout"), &port, typ)
+	require.ErrorAs(t, err, &ipld.ErrInvalidKey{})
+
+	// blocks not matching the schema fail to load
+	err = resolver.ResolveInto(ctx, r, path.FromString("/ipld/"+bad.Cid().String()+"/Settings"), &s, typ)
+	require.Error(t, err)
+}
@@ -0,0 +1,328 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	path "github.com/ipfs/go-path"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/multiformats/go-multihash"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var (
+	// ErrStaleRecord is returned when adding an IPNS record older than the
+	// record already held for its name.
+	ErrStaleRecord = errors.New("record is older than the current record")
+	// ErrInvalidIPNSName is returned for names that are not IPNS keys.
+	ErrInvalidIPNSName = errors.New("name is not an IPNS key")
+	// ErrInvalidRecord is returned for IPNS records that cannot be decoded,
+	// have no V2 signature or hold an invalid path.
+	ErrInvalidRecord = errors.New("invalid IPNS record")
+	// ErrRecordSignature is returned for IPNS records whose signature cannot
+	// be verified with the key of their name.
+	ErrRecordSignature = errors.New("invalid IPNS record signature")
+	// ErrExpiredRecord is returned for IPNS records past their end of
+	// validity.
+	ErrExpiredRecord = errors.New("IPNS record has expired")
+)
+
+// IPNSRecordResolver is a NameResolver serving signed IPNS records received
+// out of band, such as alongside a CAR or an HTTP response. Records are
+// verified against the public key of their name when added, and names
+// resolve without any network access.
+//
+// Only records with a V2 signature are accepted: their value, sequence
+// number, validity and TTL are all read from the signed CBOR data of the
+// record, as the V1 signature does not cover the sequence number nor the
+// TTL. Resolutions may be cached for the TTL of the record, bounded by its
+// end of validity.
+type IPNSRecordResolver struct {
+	mu      sync.RWMutex
+	records map[string]ipnsRecord
+}
+
+// ipnsRecord holds the signed fields of a verified IPNS record.
+type ipnsRecord struct {
+	value path.Path
+	seq   uint64
+	eol   time.Time
+	ttl   time.Duration
+}
+
+// NewIPNSRecordResolver returns an IPNSRecordResolver with no records.
+func NewIPNSRecordResolver() *IPNSRecordResolver {
+	return &IPNSRecordResolver{records: make(map[string]ipnsRecord)}
+}
+
+// AddRecord verifies the serialized IPNS record raw for name and makes name
+// resolve to its value. The record must carry a V2 signature made with the
+// key of name, be within its validity period and hold a valid path. A record
+// older than the one already held for name, by sequence number and then by
+// end of validity, is rejected with ErrStaleRecord.
+func (ir *IPNSRecordResolver) AddRecord(name string, raw []byte) error {
+	key, err := ipnsKey(name)
+	if err != nil {
+		return err
+	}
+	rec, err := verifyIPNSRecord(key, raw)
+	if err != nil {
+		return err
+	}
+	if !time.Now().Before(rec.eol) {
+		return ErrExpiredRecord
+	}
+
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	if current, ok := ir.records[string(key)]; ok {
+		switch {
+		case rec.seq < current.seq, rec.seq == current.seq && rec.eol.Before(current.eol):
+			return ErrStaleRecord
+		case rec.seq == current.seq && rec.eol.Equal(current.eol):
+			// the records cannot be ordered, keep the current one
+			return nil
+		}
+	}
+	ir.records[string(key)] = rec
+	return nil
+}
+
+// ResolveName implements NameResolver.
+func (ir *IPNSRecordResolver) ResolveName(_ context.Context, name string) (path.Path, time.Duration, error) {
+	key, err := ipnsKey(name)
+	if err != nil {
+		return "", 0, err
+	}
+
+	ir.mu.RLock()
+	rec, ok := ir.records[string(key)]
+	ir.mu.RUnlock()
+	if !ok {
+		return "", 0, fmt.Errorf("%w: no IPNS record for %s", ErrNameNotFound, name)
+	}
+
+	// the record may have expired since it was added
+	validity := time.Until(rec.eol)
+	if validity <= 0 {
+		return "", 0, ErrExpiredRecord
+	}
+	ttl := rec.ttl
+	if ttl > validity {
+		ttl = validity
+	}
+	return rec.value, ttl, nil
+}
+
+// ipnsKey returns the multihash of the public key an IPNS name stands for,
+// written either as a base58 peer ID or as a libp2p-key CID.
+func ipnsKey(name string) (multihash.Multihash, error) {
+	if strings.HasPrefix(name, "Qm") || strings.HasPrefix(name, "1") {
+		mh, err := multihash.FromB58String(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidIPNSName, err)
+		}
+		return mh, nil
+	}
+	c, err := cid.Decode(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIPNSName, err)
+	}
+	if c.Type() != cid.Libp2pKey {
+		return nil, fmt.Errorf("%w: %s is not a libp2p-key CID", ErrInvalidIPNSName, name)
+	}
+	return c.Hash(), nil
+}
+
+// Fields of the IpnsEntry protobuf message read by verifyIPNSRecord.
+const (
+	ipnsFieldPubKey      = 7
+	ipnsFieldSignatureV2 = 8
+	ipnsFieldData        = 9
+)
+
+// verifyIPNSRecord decodes the serialized IPNS record raw and verifies its
+// V2 signature against the public key whose multihash is key. Every field
+// returned comes from the signed CBOR data of the record.
+func verifyIPNSRecord(key multihash.Multihash, raw []byte) (ipnsRecord, error) {
+	var pubKey, sig, data []byte
+	for b := raw; len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return ipnsRecord{}, fmt.Errorf("%w: %s", ErrInvalidRecord, protowire.ParseError(n))
+		}
+		b = b[n:]
+		if typ == protowire.BytesType && (num == ipnsFieldPubKey || num == ipnsFieldSignatureV2 || num == ipnsFieldData) {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return ipnsRecord{}, fmt.Errorf("%w: %s", ErrInvalidRecord, protowire.ParseError(n))
+			}
+			switch num {
+			case ipnsFieldPubKey:
+				pubKey = v
+			case ipnsFieldSignatureV2:
+				sig = v
+			case ipnsFieldData:
+				data = v
+			}
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return ipnsRecord{}, fmt.Errorf("%w: %s", ErrInvalidRecord, protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+	if len(sig) == 0 || len(data) == 0 {
+		return ipnsRecord{}, fmt.Errorf("%w: no V2 signature", ErrInvalidRecord)
+	}
+
+	pk, err := ipnsPublicKey(key, pubKey)
+	if err != nil {
+		return ipnsRecord{}, err
+	}
+	if err := verifySignature(pk, append([]byte("ipns-signature:"), data...), sig); err != nil {
+		return ipnsRecord{}, err
+	}
+	return decodeIPNSData(data)
+}
+
+// ipnsPublicKey returns the serialized libp2p public key whose multihash is
+// key: the key inlined in key, or else embedded, the key embedded in the
+// record.
+func ipnsPublicKey(key multihash.Multihash, embedded []byte) ([]byte, error) {
+	dmh, err := multihash.Decode(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIPNSName, err)
+	}
+	if dmh.Code == multihash.IDENTITY {
+		return dmh.Digest, nil
+	}
+	if len(embedded) == 0 {
+		return nil, fmt.Errorf("%w: no public key for the name", ErrInvalidRecord)
+	}
+	sum, err := multihash.Sum(embedded, dmh.Code, dmh.Length)
+	if err != nil || !bytes.Equal(sum, key) {
+		return nil, fmt.Errorf("%w: the embedded public key does not match the name", ErrRecordSignature)
+	}
+	return embedded, nil
+}
+
+// Key types of libp2p public keys.
+const (
+	keyTypeRSA     = 0
+	keyTypeEd25519 = 1
+	keyTypeECDSA   = 3
+)
+
+// verifySignature verifies sig, the signature of msg made with the private
+// key of the serialized libp2p public key pk. Secp256k1 keys are not
+// supported.
+func verifySignature(pk, msg, sig []byte) error {
+	var keyType uint64
+	var keyData []byte
+	for b := pk; len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("%w: invalid public key", ErrRecordSignature)
+		}
+		b = b[n:]
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			keyType, n = protowire.ConsumeVarint(b)
+		case num == 2 && typ == protowire.BytesType:
+			keyData, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("%w: invalid public key", ErrRecordSignature)
+		}
+		b = b[n:]
+	}
+
+	hashed := sha256.Sum256(msg)
+	ok := false
+	switch keyType {
+	case keyTypeEd25519:
+		ok = len(keyData) == ed25519.PublicKeySize && ed25519.Verify(keyData, msg, sig)
+	case keyTypeRSA, keyTypeECDSA:
+		pub, err := x509.ParsePKIXPublicKey(keyData)
+		if err != nil {
+			return fmt.Errorf("%w: invalid public key: %s", ErrRecordSignature, err)
+		}
+		switch pub := pub.(type) {
+		case *rsa.PublicKey:
+			ok = keyType == keyTypeRSA && rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], sig) == nil
+		case *ecdsa.PublicKey:
+			ok = keyType == keyTypeECDSA && ecdsa.VerifyASN1(pub, hashed[:], sig)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported key type %d", ErrRecordSignature, keyType)
+	}
+	if !ok {
+		return ErrRecordSignature
+	}
+	return nil
+}
+
+// decodeIPNSData decodes the signed CBOR data of an IPNS record.
+func decodeIPNSData(data []byte) (ipnsRecord, error) {
+	nb := basicnode.Prototype.Map.NewBuilder()
+	if err := dagcbor.Decode(nb, bytes.NewReader(data)); err != nil {
+		return ipnsRecord{}, fmt.Errorf("%w: %s", ErrInvalidRecord, err)
+	}
+	nd := nb.Build()
+
+	field := func(name string) ipld.Node {
+		v, err := nd.LookupByString(name)
+		if err != nil {
+			return basicnode.NewString("")
+		}
+		return v
+	}
+	value, err := field("Value").AsBytes()
+	if err != nil {
+		return ipnsRecord{}, fmt.Errorf("%w: no value", ErrInvalidRecord)
+	}
+	validity, err := field("Validity").AsBytes()
+	if err != nil {
+		return ipnsRecord{}, fmt.Errorf("%w: no validity", ErrInvalidRecord)
+	}
+	if validityType, err := field("ValidityType").AsInt(); err != nil || validityType != 0 {
+		return ipnsRecord{}, fmt.Errorf("%w: unsupported validity type", ErrInvalidRecord)
+	}
+	seq, err := field("Sequence").AsInt()
+	if err != nil || seq < 0 {
+		return ipnsRecord{}, fmt.Errorf("%w: no sequence number", ErrInvalidRecord)
+	}
+	ttl, err := field("TTL").AsInt()
+	if err != nil || ttl < 0 {
+		return ipnsRecord{}, fmt.Errorf("%w: no TTL", ErrInvalidRecord)
+	}
+
+	eol, err := time.Parse(time.RFC3339Nano, string(validity))
+	if err != nil {
+		return ipnsRecord{}, fmt.Errorf("%w: %s", ErrInvalidRecord, err)
+	}
+	p, err := path.ParsePath(string(value))
+	if err != nil {
+		return ipnsRecord{}, fmt.Errorf("%w: %s", ErrInvalidRecord, err)
+	}
+	return ipnsRecord{value: p, seq: uint64(seq), eol: eol, ttl: time.Duration(ttl)}, nil
+}
@@ -0,0 +1,201 @@
+package resolver_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ipnsKey is a key signing IPNS records in tests.
+type ipnsKey struct {
+	// pub is the serialized libp2p public key.
+	pub  []byte
+	sign func(msg []byte) []byte
+}
+
+func ed25519IPNSKey(t *testing.T) ipnsKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return ipnsKey{
+		pub:  libp2pPublicKey(1, pub),
+		sign: func(msg []byte) []byte { return ed25519.Sign(priv, msg) },
+	}
+}
+
+func rsaIPNSKey(t *testing.T) ipnsKey {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	require.NoError(t, err)
+	return ipnsKey{
+		pub: libp2pPublicKey(0, der),
+		sign: func(msg []byte) []byte {
+			hashed := sha256.Sum256(msg)
+			sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, hashed[:])
+			require.NoError(t, err)
+			return sig
+		},
+	}
+}
+
+func libp2pPublicKey(keyType uint64, data []byte) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, keyType)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	return protowire.AppendBytes(b, data)
+}
+
+// name returns the IPNS name of k, as a libp2p-key CID. Small keys are
+// inlined in the name, others are hashed.
+func (k ipnsKey) name(t *testing.T) (multihash.Multihash, string) {
+	t.Helper()
+	code := uint64(multihash.IDENTITY)
+	if len(k.pub) > 42 {
+		code = multihash.SHA2_256
+	}
+	mh, err := multihash.Sum(k.pub, code, -1)
+	require.NoError(t, err)
+	return mh, cid.NewCidV1(cid.Libp2pKey, mh).String()
+}
+
+// ipnsRecord returns an IPNS record signed by k, with the V1 fields set along
+// the signed CBOR data of V2 records, unless v1Only is set.
+func ipnsRecord(t *testing.T, k ipnsKey, value string, seq uint64, eol time.Time, ttl time.Duration, v1Only bool) []byte {
+	t.Helper()
+	validity := eol.UTC().Format(time.RFC3339Nano)
+	nd, err := qp.BuildMap(basicnode.Prototype.Map, 5, func(ma ipld.MapAssembler) {
+		qp.MapEntry(ma, "Value", qp.Bytes([]byte(value)))
+		qp.MapEntry(ma, "Validity", qp.Bytes([]byte(validity)))
+		qp.MapEntry(ma, "ValidityType", qp.Int(0))
+		qp.MapEntry(ma, "Sequence", qp.Int(int64(seq)))
+		qp.MapEntry(ma, "TTL", qp.Int(int64(ttl)))
+	})
+	require.NoError(t, err)
+	data := new(bytes.Buffer)
+	require.NoError(t, dagcbor.Encode(nd, data))
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, []byte(value))
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendBytes(b, k.sign([]byte(value+validity+"0")))
+	b = protowire.AppendTag(b, 3, protowire.VarintType)
+	b = protowire.AppendVarint(b, 0)
+	b = protowire.AppendTag(b, 4, protowire.BytesType)
+	b = protowire.AppendBytes(b, []byte(validity))
+	b = withRecordField(b, 5, seq)
+	b = withRecordField(b, 6, uint64(ttl))
+	if len(k.pub) > 42 {
+		b = protowire.AppendTag(b, 7, protowire.BytesType)
+		b = protowire.AppendBytes(b, k.pub)
+	}
+	if !v1Only {
+		b = protowire.AppendTag(b, 8, protowire.BytesType)
+		b = protowire.AppendBytes(b, k.sign(append([]byte("ipns-signature:"), data.Bytes()...)))
+		b = protowire.AppendTag(b, 9, protowire.BytesType)
+		b = protowire.AppendBytes(b, data.Bytes())
+	}
+	return b
+}
+
+// withRecordField sets the varint field num of the record raw to v, as the
+// last value of a protobuf field wins.
+func withRecordField(raw []byte, num protowire.Number, v uint64) []byte {
+	raw = protowire.AppendTag(append([]byte{}, raw...), num, protowire.VarintType)
+	return protowire.AppendVarint(raw, v)
+}
+
+func TestIPNSRecordResolver(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("b", b))
+	aPath := "/ipfs/" + a.Cid().String()
+	bPath := "/ipfs/" + b.Cid().String()
+
+	key := ed25519IPNSKey(t)
+	mh, name := key.name(t)
+	other := ed25519IPNSKey(t)
+
+	eol := time.Now().Add(time.Hour)
+	ir := resolver.NewIPNSRecordResolver()
+
+	_, _, err := ir.ResolveName(ctx, name)
+	require.ErrorIs(t, err, resolver.ErrNameNotFound)
+
+	require.ErrorIs(t, ir.AddRecord(name, ipnsRecord(t, other, aPath, 1, eol, time.Minute, false)), resolver.ErrRecordSignature)
+	require.ErrorIs(t, ir.AddRecord(name, ipnsRecord(t, key, aPath, 1, time.Now().Add(-time.Minute), time.Minute, false)), resolver.ErrExpiredRecord)
+	require.ErrorIs(t, ir.AddRecord(name, ipnsRecord(t, key, "not a path", 1, eol, time.Minute, false)), resolver.ErrInvalidRecord)
+	require.ErrorIs(t, ir.AddRecord("notakey", ipnsRecord(t, key, aPath, 1, eol, time.Minute, false)), resolver.ErrInvalidIPNSName)
+	require.ErrorIs(t, ir.AddRecord(name, []byte("garbage")), resolver.ErrInvalidRecord)
+	// records without a V2 signature are rejected
+	require.ErrorIs(t, ir.AddRecord(name, ipnsRecord(t, key, aPath, 1, eol, time.Minute, true)), resolver.ErrInvalidRecord)
+
+	old := ipnsRecord(t, key, bPath, 1, eol, time.Minute, false)
+	require.NoError(t, ir.AddRecord(name, ipnsRecord(t, key, aPath, 5, eol, time.Minute, false)))
+	p, ttl, err := ir.ResolveName(ctx, name)
+	require.NoError(t, err)
+	require.Equal(t, path.FromString(aPath), p)
+	require.Equal(t, time.Minute, ttl)
+
+	// the unsigned protobuf fields are ignored: an old record cannot be
+	// replayed with a higher sequence number, nor a longer TTL
+	require.ErrorIs(t, ir.AddRecord(name, withRecordField(old, 5, 99)), resolver.ErrStaleRecord)
+
+	// records are ordered by sequence number, whatever the encoding of the
+	// name
+	require.ErrorIs(t, ir.AddRecord(mh.B58String(), old), resolver.ErrStaleRecord)
+	require.NoError(t, ir.AddRecord(mh.B58String(), withRecordField(ipnsRecord(t, key, bPath, 6, eol, 2*time.Hour, false), 6, uint64(time.Second))))
+	p, ttl, err = ir.ResolveName(ctx, name)
+	require.NoError(t, err)
+	require.Equal(t, path.FromString(bPath), p)
+	// the TTL is the signed one, bounded by the end of validity of the
+	// record
+	require.Greater(t, ttl, time.Minute)
+	require.LessOrEqual(t, ttl, time.Hour)
+
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b), resolver.WithNameResolver(ir))
+	last, _, err := r.ResolveToLastNode(ctx, path.FromString("/ipns/"+name))
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), last)
+}
+
+func TestIPNSRecordResolverEmbeddedKey(t *testing.T) {
+	ctx := context.Background()
+
+	key := rsaIPNSKey(t)
+	_, name := key.name(t)
+	ir := resolver.NewIPNSRecordResolver()
+
+	value := "/ipfs/" + randNode().Cid().String()
+	require.NoError(t, ir.AddRecord(name, ipnsRecord(t, key, value, 1, time.Now().Add(time.Hour), time.Minute, false)))
+	p, _, err := ir.ResolveName(ctx, name)
+	require.NoError(t, err)
+	require.Equal(t, path.FromString(value), p)
+
+	// the embedded key must be the one of the name
+	_, other := rsaIPNSKey(t).name(t)
+	require.ErrorIs(t, ir.AddRecord(other, ipnsRecord(t, key, value, 1, time.Now().Add(time.Hour), time.Minute, false)), resolver.ErrRecordSignature)
+}
@@ -0,0 +1,212 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	cid "github.com/ipfs/go-cid"
+	path "github.com/ipfs/go-path"
+)
+
+// DefaultNameRecursionLimit is the maximum number of names resolved for a
+// single path when a name points to another name.
+const DefaultNameRecursionLimit = 32
+
+// nameCacheSize is the number of name resolutions cached by a resolver.
+const nameCacheSize = 1024
+
+var (
+	// ErrNameNotFound is returned by a NameResolver for names it cannot
+	// resolve.
+	ErrNameNotFound = errors.New("name not found")
+	// ErrNameRecursionLimit is returned when resolving the root of a path
+	// goes through more names than the recursion limit allows.
+	ErrNameRecursionLimit = errors.New("name recursion limit exceeded")
+)
+
+// NameResolver resolves mutable names, such as IPNS names or DNSLink
+// domains, to paths.
+type NameResolver interface {
+	// ResolveName returns the path the name points to and how long that
+	// result may be cached. The path may itself be an /ipns/ path. A zero
+	// TTL means the result must not be cached.
+	ResolveName(ctx context.Context, name string) (path.Path, time.Duration, error)
+}
+
+// NameHop is the resolution of a name met while resolving the root of a
+// path.
+type NameHop struct {
+	// Name is the name resolved, without the /ipns/ prefix.
+	Name string
+	// Value is the path the name points to.
+	Value path.Path
+	// TTL is how long the resolution remains valid.
+	TTL time.Duration
+}
+
+// MemoryNameResolver is a NameResolver serving names published in memory.
+type MemoryNameResolver struct {
+	mu      sync.RWMutex
+	records map[string]memoryRecord
+}
+
+type memoryRecord struct {
+	value path.Path
+	ttl   time.Duration
+}
+
+// NewMemoryNameResolver returns a MemoryNameResolver with no names.
+func NewMemoryNameResolver() *MemoryNameResolver {
+	return &MemoryNameResolver{records: make(map[string]memoryRecord)}
+}
+
+// Publish points name to value, with the given TTL, replacing any previous
+// value.
+func (m *MemoryNameResolver) Publish(name string, value path.Path, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[name] = memoryRecord{value: value, ttl: ttl}
+}
+
+// ResolveName implements NameResolver.
+func (m *MemoryNameResolver) ResolveName(_ context.Context, name string) (path.Path, time.Duration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rec, ok := m.records[name]
+	if !ok {
+		return "", 0, fmt.Errorf("%w: %s", ErrNameNotFound, name)
+	}
+	return rec.value, rec.ttl, nil
+}
+
+// nameCache caches name resolutions until their TTL expires.
+type nameCache struct {
+	cache *lru.Cache
+	clock Clock
+}
+
+type nameCacheEntry struct {
+	value   path.Path
+	expires time.Time
+}
+
+func newNameCache(clock Clock) *nameCache {
+	cache, err := lru.New(nameCacheSize)
+	if err != nil {
+		panic(err)
+	}
+	return &nameCache{cache: cache, clock: clock}
+}
+
+func (nc *nameCache) get(name string) (NameHop, bool) {
+	v, ok := nc.cache.Get(name)
+	if !ok {
+		return NameHop{}, false
+	}
+	e := v.(nameCacheEntry)
+	ttl := e.expires.Sub(nc.clock.Now())
+	if ttl <= 0 {
+		nc.cache.Remove(name)
+		return NameHop{}, false
+	}
+	return NameHop{Name: name, Value: e.value, TTL: ttl}, true
+}
+
+func (nc *nameCache) add(hop NameHop) {
+	if hop.TTL <= 0 {
+		return
+	}
+	nc.cache.Add(hop.Name, nameCacheEntry{value: hop.Value, expires: nc.clock.Now().Add(hop.TTL)})
+}
+
+// Path namespaces with their own pathing semantics.
+const (
+	nsIPFS = "ipfs"
+	nsIPLD = "ipld"
+)
+
+// pathNamespace returns the namespace of the immutable path fpath resolves
+// to, given the names resolved for its root. Paths without a namespace are
+// /ipfs/ paths.
+func pathNamespace(fpath path.Path, hops []NameHop) string {
+	if len(hops) > 0 {
+		fpath = hops[len(hops)-1].Value
+	}
+	if segments := fpath.Segments(); len(segments) > 0 && segments[0] == nsIPLD {
+		return nsIPLD
+	}
+	return nsIPFS
+}
+
+// splitPath splits fpath into its root cid and the segments after it, as
+// path.SplitAbsPath does, after resolving the names at the root of /ipns/
+// paths. It returns the names resolved along the way. The resolution of
+// each name is bounded by blockTimeout, if positive.
+func (r *basicResolver) splitPath(ctx context.Context, fpath path.Path, blockTimeout time.Duration) (cid.Cid, []string, []NameHop, error) {
+	var hops []NameHop
+	for {
+		segments := fpath.Segments()
+		if r.opts.nameResolver == nil || len(segments) < 2 || segments[0] != "ipns" {
+			c, p, err := path.SplitAbsPath(fpath)
+			return c, p, hops, err
+		}
+		if len(hops) >= r.opts.nameRecursionLimit {
+			return cid.Cid{}, nil, hops, fmt.Errorf("%w: resolving %s", ErrNameRecursionLimit, fpath)
+		}
+
+		hop, err := r.resolveName(ctx, segments[1], blockTimeout)
+		if err != nil {
+			return cid.Cid{}, nil, hops, fmt.Errorf("could not resolve name %q: %w", segments[1], err)
+		}
+		hops = append(hops, hop)
+
+		fpath, err = path.FromSegments("/", append(hop.Value.Segments(), segments[2:]...)...)
+		if err != nil {
+			return cid.Cid{}, nil, hops, err
+		}
+	}
+}
+
+// resolveRoot resolves the names at the root of fpath within the resolution
+// scope of w, ctx, as splitPath does, and makes w resolve the segments of
+// fpath with the pathing of the namespace of its root.
+func (r *basicResolver) resolveRoot(ctx context.Context, w *walker, fpath path.Path) (cid.Cid, []string, []NameHop, error) {
+	c, p, hops, err := r.splitPath(ctx, fpath, w.blockTimeout)
+	if err != nil {
+		return cid.Cid{}, nil, hops, err
+	}
+	r.enterNamespace(w, pathNamespace(fpath, hops))
+	return c, p, hops, nil
+}
+
+// resolveName resolves name with the resolver's NameResolver, or from the
+// cache, within blockTimeout if positive. DNSLink names are resolved and
+// cached in their canonical ASCII form, so that equivalent spellings share
+// their resolution.
+func (r *basicResolver) resolveName(ctx context.Context, name string, blockTimeout time.Duration) (NameHop, error) {
+	if strings.Contains(name, ".") {
+		if ascii, err := path.DNSLinkNameToASCII(name); err == nil {
+			name = ascii
+		}
+	}
+	if hop, ok := r.names.get(name); ok {
+		return hop, nil
+	}
+	if blockTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, blockTimeout)
+		defer cancel()
+	}
+	value, ttl, err := r.opts.nameResolver.ResolveName(ctx, name)
+	if err != nil {
+		return NameHop{}, err
+	}
+	hop := NameHop{Name: name, Value: value, TTL: ttl}
+	r.names.add(hop)
+	return hop, nil
+}
@@ -0,0 +1,122 @@
+package resolver_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingNameResolver resolves no name until ctx is done.
+type blockingNameResolver struct{}
+
+func (blockingNameResolver) ResolveName(ctx context.Context, _ string) (path.Path, time.Duration, error) {
+	<-ctx.Done()
+	return "", 0, ctx.Err()
+}
+
+func TestNameResolution(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	require.NoError(t, b.AddNodeLink("c", c))
+	require.NoError(t, a.AddNodeLink("b", b))
+
+	names := resolver.NewMemoryNameResolver()
+	names.Publish("site", path.FromString("/ipfs/"+a.Cid().String()+"/b"), time.Hour)
+	names.Publish("alias", path.FromString("/ipns/site"), 0)
+	names.Publish("loop", path.FromString("/ipns/loop"), 0)
+
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b, c), resolver.WithNameResolver(names), resolver.WithNameRecursionLimit(4))
+
+	t.Run("chain", func(t *testing.T) {
+		res, err := resolver.Resolve(ctx, r, path.FromString("/ipns/alias/c"))
+		require.NoError(t, err)
+		require.Equal(t, c.Cid(), res.Last().Block)
+		require.Len(t, res.Names, 2)
+		require.Equal(t, resolver.NameHop{Name: "alias", Value: path.FromString("/ipns/site")}, res.Names[0])
+		require.Equal(t, "site", res.Names[1].Name)
+		require.Equal(t, path.FromString("/ipfs/"+a.Cid().String()+"/b"), res.Names[1].Value)
+
+		last, rest, err := r.ResolveToLastNode(ctx, path.FromString("/ipns/alias/c"))
+		require.NoError(t, err)
+		require.Equal(t, c.Cid(), last)
+		require.Empty(t, rest)
+	})
+
+	t.Run("cached for the TTL", func(t *testing.T) {
+		names.Publish("site", path.FromString("/ipfs/"+c.Cid().String()), time.Hour)
+		last, _, err := r.ResolveToLastNode(ctx, path.FromString("/ipns/site"))
+		require.NoError(t, err)
+		require.Equal(t, b.Cid(), last)
+
+		// names with no TTL are resolved again
+		names.Publish("alias", path.FromString("/ipfs/"+c.Cid().String()), 0)
+		last, _, err = r.ResolveToLastNode(ctx, path.FromString("/ipns/alias"))
+		require.NoError(t, err)
+		require.Equal(t, c.Cid(), last)
+	})
+
+	t.Run("recursion limit", func(t *testing.T) {
+		_, _, err := r.ResolveToLastNode(ctx, path.FromString("/ipns/loop"))
+		require.ErrorIs(t, err, resolver.ErrNameRecursionLimit)
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		_, _, err := r.ResolveToLastNode(ctx, path.FromString("/ipns/unknown"))
+		require.ErrorIs(t, err, resolver.ErrNameNotFound)
+	})
+
+	t.Run("no name resolver", func(t *testing.T) {
+		r := resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b, c))
+		_, _, err := r.ResolveToLastNode(ctx, path.FromString("/ipns/site"))
+		require.Error(t, err)
+	})
+}
+
+func TestNameResolutionTimeout(t *testing.T) {
+	p := path.FromString("/ipns/example.com")
+
+	for name, opt := range map[string]resolver.Option{
+		"timeout":       resolver.WithTimeout(20 * time.Millisecond),
+		"block timeout": resolver.WithBlockTimeout(20 * time.Millisecond),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := resolver.NewBasicResolver(unixfsFetcherFactory(t), resolver.WithNameResolver(blockingNameResolver{}), opt)
+
+			_, _, err := r.ResolveToLastNode(context.Background(), p)
+			require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+			_, _, err = r.ResolvePath(context.Background(), p)
+			require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+			_, err = r.ResolvePathComponents(context.Background(), p)
+			require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+			_, err = resolver.ResolveToImmutable(context.Background(), r, p, resolver.KeepSegments)
+			require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+		})
+	}
+}
+
+func TestDNSLinkNameCache(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	names := resolver.NewMemoryNameResolver()
+	names.Publish("example.com", path.FromCid(a.Cid()), time.Hour)
+	nr := &countingNameResolver{NameResolver: names}
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, a), resolver.WithNameResolver(nr))
+
+	// equivalent spellings of a DNSLink name share its resolution
+	for _, name := range []string{"Example.COM", "example.com", "EXAMPLE.com"} {
+		res, err := resolver.Resolve(ctx, r, path.FromString("/ipns/"+name))
+		require.NoError(t, err)
+		require.Equal(t, a.Cid(), res.Last().Block)
+		require.Equal(t, "example.com", res.Names[0].Name)
+	}
+	require.Equal(t, 1, nr.lookups)
+}
@@ -0,0 +1,54 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceReification(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("foo", b))
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b))
+
+	// /ipfs/ paths use UnixFS names
+	c, rest, err := r.ResolveToLastNode(ctx, path.FromString("/ipfs/"+a.Cid().String()+"/foo"))
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), c)
+	require.Empty(t, rest)
+
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString("/ipfs/"+a.Cid().String()+"/Links/0/Hash"))
+	require.ErrorAs(t, err, &resolver.ErrNoLink{})
+
+	// /ipld/ paths use the raw DAG-PB data model
+	c, rest, err = r.ResolveToLastNode(ctx, path.FromString("/ipld/"+a.Cid().String()+"/Links/0/Hash"))
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), c)
+	require.Empty(t, rest)
+
+	c, rest, err = r.ResolveToLastNode(ctx, path.FromString("/ipld/"+a.Cid().String()+"/Links/0/Name"))
+	require.NoError(t, err)
+	require.Equal(t, a.Cid(), c)
+	require.Equal(t, []string{"Links", "0", "Name"}, rest)
+
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString("/ipld/"+a.Cid().String()+"/foo"))
+	require.Error(t, err)
+
+	// names pointing to /ipld/ paths keep the raw data model
+	names := resolver.NewMemoryNameResolver()
+	names.Publish("raw", path.FromString("/ipld/"+a.Cid().String()), 0)
+	r = resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b), resolver.WithNameResolver(names))
+	c, _, err = r.ResolveToLastNode(ctx, path.FromString("/ipns/raw/Links/0/Hash"))
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), c)
+
+	ip, err := resolver.ResolveToImmutable(ctx, r, path.FromString("/ipns/raw/Links/0/Hash"), resolver.KeepSegments)
+	require.NoError(t, err)
+	require.Equal(t, path.FromString("/ipld/"+a.Cid().String()+"/Links/0/Hash"), ip.Path)
+}
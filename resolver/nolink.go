@@ -0,0 +1,222 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-unixfsnode/data"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSuggestionDistance is the largest edit distance between a missing name
+// and a name suggested in its place.
+const maxSuggestionDistance = 2
+
+// NoLinkDetails describes the node under which a link is missing.
+type NoLinkDetails struct {
+	// Parent is the path to the node missing the link. It is empty when the
+	// resolution did not start from the root of a path.
+	Parent path.Path
+	// Names lists names found under the node, sorted. It holds at most the
+	// number of names configured with WithNoLinkDetails.
+	Names []string
+	// Complete is true if Names lists every name under the node.
+	Complete bool
+	// Suggestion is the name under the node closest to the missing one, if
+	// one differs from it only by case, by Unicode normalization or by a
+	// small edit distance. It is picked among every name of the blocks
+	// loaded, including those left out of Names.
+	Suggestion string
+}
+
+// pbShard is a dag-pb node, reified or not, that may be a HAMT shard.
+type pbShard interface {
+	FieldLinks() dagpb.PBLinks
+	FieldData() dagpb.MaybeBytes
+}
+
+// noLink returns the ErrNoLink for the missing name under the node cur,
+// reached by resolving parent from the node from.
+func (w *walker) noLink(ctx context.Context, from ResolvedSegment, parent []string, cur ResolvedSegment, name string) ErrNoLink {
+	err := ErrNoLink{Name: name, Node: cur.Block}
+	if w.noLinkNames <= 0 {
+		return err
+	}
+
+	// the suggestion is picked among every name of the blocks loaded, before
+	// cutting the list
+	names, complete := w.listNames(ctx, cur.Node)
+	details := &NoLinkDetails{
+		Parent:     w.prefix(from, parent),
+		Names:      names,
+		Complete:   complete,
+		Suggestion: closestName(name, names),
+	}
+	if len(names) > w.noLinkNames {
+		details.Names, details.Complete = names[:w.noLinkNames], false
+	}
+	err.Details = details
+	return err
+}
+
+// prefix returns the path made of segments after the block root from, or ""
+// if from is not the root of a block.
+func (w *walker) prefix(from ResolvedSegment, segments []string) path.Path {
+	if !from.Block.Defined() || from.Depth != 0 {
+		return ""
+	}
+	ns := w.ns
+	if ns == "" {
+		ns = nsIPFS
+	}
+	p, err := path.FromSegments("/"+ns+"/", append([]string{from.Block.String()}, segments...)...)
+	if err != nil {
+		return ""
+	}
+	return p
+}
+
+// listNames lists the names under the map node nd, sorted, and reports
+// whether it listed them all. The blocks of a HAMT shard are loaded as long
+// as noLinkShardBlocks allows.
+func (w *walker) listNames(ctx context.Context, nd ipld.Node) ([]string, bool) {
+	var names []string
+	complete := true
+	if shard, ok := nd.(pbShard); ok && hamtPadLen(shard) > 0 {
+		fetches := w.noLinkShardBlocks
+		complete = w.listShardNames(ctx, shard, &names, &fetches)
+	} else {
+		if nd.Kind() != ipld.Kind_Map {
+			return nil, false
+		}
+		for it := nd.MapIterator(); !it.Done(); {
+			k, _, err := it.Next()
+			if err != nil {
+				complete = false
+				break
+			}
+			if s, err := k.AsString(); err == nil {
+				names = append(names, s)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, complete
+}
+
+// listShardNames adds the names of the HAMT shard to names, loading child
+// shards while fetches is positive. It returns false if some names were left
+// out.
+func (w *walker) listShardNames(ctx context.Context, shard pbShard, names *[]string, fetches *int) bool {
+	padLen := hamtPadLen(shard)
+	complete := true
+	for itr := shard.FieldLinks().Iterator(); !itr.Done(); {
+		_, lnk := itr.Next()
+		if !lnk.FieldName().Exists() {
+			continue
+		}
+		name := lnk.FieldName().Must().String()
+		if len(name) > padLen {
+			*names = append(*names, name[padLen:])
+			continue
+		}
+
+		// links named with just the bucket prefix point to child shards
+		clnk, ok := lnk.FieldHash().Link().(cidlink.Link)
+		if !ok || *fetches <= 0 {
+			complete = false
+			continue
+		}
+		*fetches--
+		nd, err := w.loadAs(ctx, clnk, dagpb.Type.PBNode)
+		if err != nil {
+			log.Debugf("could not load HAMT shard %s to list names: %s", clnk, err)
+			complete = false
+			continue
+		}
+		child, ok := nd.(pbShard)
+		if !ok || hamtPadLen(child) == 0 {
+			complete = false
+			continue
+		}
+		if !w.listShardNames(ctx, child, names, fetches) {
+			complete = false
+		}
+	}
+	return complete
+}
+
+// hamtPadLen returns the length of the bucket prefix of the link names of
+// shard, or 0 if it is not a UnixFS HAMT shard.
+func hamtPadLen(shard pbShard) int {
+	if !shard.FieldData().Exists() {
+		return 0
+	}
+	ufsData, err := data.DecodeUnixFSData(shard.FieldData().Must().Bytes())
+	if err != nil || ufsData.FieldDataType().Int() != data.Data_HAMTShard || !ufsData.FieldFanout().Exists() {
+		return 0
+	}
+	return len(fmt.Sprintf("%X", ufsData.FieldFanout().Must().Int()-1))
+}
+
+// closestName returns the name of names closest to name: one differing only
+// by case or Unicode normalization, or else the one at the smallest edit
+// distance, if that distance is small. It returns "" if none is close.
+func closestName(name string, names []string) string {
+	folded := foldName(name)
+	best, bestDist := "", maxSuggestionDistance+1
+	for _, n := range names {
+		if n == name {
+			continue
+		}
+		f := foldName(n)
+		if f == folded {
+			return n
+		}
+		if d := editDistance(folded, f); d < bestDist && d < len([]rune(folded)) {
+			best, bestDist = n, d
+		}
+	}
+	return best
+}
+
+// foldName normalizes name for comparisons ignoring case and Unicode
+// normalization.
+func foldName(name string) string {
+	return strings.ToLower(norm.NFC.String(name))
+}
+
+// editDistance returns the Levenshtein distance between a and b, in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
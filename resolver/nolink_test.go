@@ -0,0 +1,118 @@
+package resolver_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	merkledag "github.com/ipfs/go-merkledag"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/ipfs/go-unixfsnode/data"
+	"github.com/ipfs/go-unixfsnode/data/builder"
+	"github.com/stretchr/testify/require"
+)
+
+func noLinkDetails(t *testing.T, err error) *resolver.NoLinkDetails {
+	t.Helper()
+	var errNoLink resolver.ErrNoLink
+	require.True(t, errors.As(err, &errNoLink), "expected ErrNoLink, got %v", err)
+	require.NotNil(t, errNoLink.Details)
+	return errNoLink.Details
+}
+
+func TestNoLinkDetails(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	b := randNode()
+	readme := randNode()
+	docs := randNode()
+	require.NoError(t, b.AddNodeLink("README.md", readme))
+	require.NoError(t, b.AddNodeLink("docs", docs))
+	require.NoError(t, a.AddNodeLink("b", b))
+	nodes := []*merkledag.ProtoNode{a, b, readme, docs}
+	root := "/ipfs/" + a.Cid().String()
+
+	// disabled by default
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, nodes...))
+	_, _, err := r.ResolveToLastNode(ctx, path.FromString(root+"/b/readme.md"))
+	var errNoLink resolver.ErrNoLink
+	require.ErrorAs(t, err, &errNoLink)
+	require.Nil(t, errNoLink.Details)
+
+	r = resolver.NewBasicResolver(unixfsFetcherFactory(t, nodes...), resolver.WithNoLinkDetails(10, 0))
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString(root+"/b/readme.md"))
+	details := noLinkDetails(t, err)
+	require.Equal(t, path.FromString(root+"/b"), details.Parent)
+	require.Equal(t, []string{"README.md", "docs"}, details.Names)
+	require.True(t, details.Complete)
+	require.Equal(t, "README.md", details.Suggestion)
+	require.EqualError(t, err, `no link named "readme.md" under `+b.Cid().String()+` at `+root+`/b; did you mean "README.md"?`)
+
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString(root+"/b/dcs/x"))
+	require.Equal(t, "docs", noLinkDetails(t, err).Suggestion)
+
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString(root+"/b/unrelated"))
+	require.Empty(t, noLinkDetails(t, err).Suggestion)
+
+	// names differing by Unicode normalization only
+	cafe := randNode()
+	dir := randNode()
+	require.NoError(t, dir.AddNodeLink("caf\u00e9", cafe))
+	r = resolver.NewBasicResolver(unixfsFetcherFactory(t, dir, cafe), resolver.WithNoLinkDetails(10, 0))
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString("/ipfs/"+dir.Cid().String()+"/cafe\u0301"))
+	require.Equal(t, "caf\u00e9", noLinkDetails(t, err).Suggestion)
+
+	// the number of names listed is bounded, but not the names suggested
+	r = resolver.NewBasicResolver(unixfsFetcherFactory(t, nodes...), resolver.WithNoLinkDetails(1, 0))
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString(root+"/b/docz"))
+	details = noLinkDetails(t, err)
+	require.Equal(t, []string{"README.md"}, details.Names)
+	require.False(t, details.Complete)
+	require.Equal(t, "docs", details.Suggestion)
+}
+
+// hamtShard returns a UnixFS HAMT shard node with the given links.
+func hamtShard(t *testing.T, links map[string]*merkledag.ProtoNode) *merkledag.ProtoNode {
+	t.Helper()
+	ufs, err := builder.BuildUnixFS(func(b *builder.Builder) {
+		builder.DataType(b, data.Data_HAMTShard)
+		builder.HashType(b, 0x22)
+		builder.Fanout(b, 256)
+		builder.Data(b, make([]byte, 32))
+	})
+	require.NoError(t, err)
+	shard := new(merkledag.ProtoNode)
+	shard.SetData(data.EncodeUnixFSData(ufs))
+	for name, nd := range links {
+		require.NoError(t, shard.AddNodeLink(name, nd))
+	}
+	return shard
+}
+
+func TestNoLinkDetailsSharded(t *testing.T) {
+	ctx := context.Background()
+
+	foo := randNode()
+	bar := randNode()
+	child := hamtShard(t, map[string]*merkledag.ProtoNode{"A2bar": bar})
+	shard := hamtShard(t, map[string]*merkledag.ProtoNode{"00foo": foo, "01": child})
+	p := path.FromString("/ipfs/" + shard.Cid().String() + "/barr")
+
+	// child shards are not fetched without a limit allowing it
+	factory := unixfsFetcherFactory(t, shard, child, foo, bar)
+	r := resolver.NewBasicResolver(factory, resolver.WithNoLinkDetails(10, 0))
+	_, _, err := r.ResolveToLastNode(ctx, p)
+	details := noLinkDetails(t, err)
+	require.Equal(t, []string{"foo"}, details.Names)
+	require.False(t, details.Complete)
+	require.Empty(t, details.Suggestion)
+
+	r = resolver.NewBasicResolver(factory, resolver.WithNoLinkDetails(10, 1))
+	_, _, err = r.ResolveToLastNode(ctx, p)
+	details = noLinkDetails(t, err)
+	require.Equal(t, []string{"bar", "foo"}, details.Names)
+	require.True(t, details.Complete)
+	require.Equal(t, "bar", details.Suggestion)
+}
@@ -0,0 +1,79 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-fetcher"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	path "github.com/ipfs/go-path"
+)
+
+// LocalBlockstore holds blocks available locally. It is satisfied by a
+// blockstore.Blockstore, and must report missing blocks with
+// blockstore.ErrNotFound.
+type LocalBlockstore interface {
+	Get(ctx context.Context, c cid.Cid) (blocks.Block, error)
+}
+
+// NewOfflineFetcherFactory returns a fetcher.Factory loading blocks from bs
+// only, never from the network. Resolutions using it fail as soon as a block
+// is missing from bs, with an ErrMissingBlock, instead of waiting for the
+// block to be found.
+//
+// Blocks are trusted to match their cid, as bs is local.
+func NewOfflineFetcherFactory(bs LocalBlockstore) fetcher.Factory {
+	return newBlockGetterFetcher(localBlockGetter{bs}, nil)
+}
+
+// localBlockGetter is a BlockGetter for the blocks of a LocalBlockstore.
+type localBlockGetter struct {
+	bs LocalBlockstore
+}
+
+func (g localBlockGetter) GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	blk, err := g.bs.Get(ctx, c)
+	if errors.Is(err, blockstore.ErrNotFound) {
+		return nil, ErrMissingBlock{Cid: c}
+	}
+	return blk, err
+}
+
+// ErrMissingBlock is returned by resolutions with a fetcher factory created
+// by NewOfflineFetcherFactory when a block they need is not available
+// locally.
+type ErrMissingBlock struct {
+	// Cid is the cid of the missing block.
+	Cid cid.Cid
+	// Prefix is the prefix of the path whose resolution needs the block. It
+	// is empty when the resolution did not start from the root of a path.
+	Prefix path.Path
+	// Remaining is the number of path segments after Prefix left to
+	// resolve.
+	Remaining int
+}
+
+// Error implements the Error interface for ErrMissingBlock with a useful
+// human readable message.
+func (e ErrMissingBlock) Error() string {
+	if e.Prefix == "" {
+		return fmt.Sprintf("block %s is not available locally", e.Cid)
+	}
+	return fmt.Sprintf("block %s is not available locally, needed to resolve %s (%d segments remaining)", e.Cid, e.Prefix, e.Remaining)
+}
+
+// missingBlock returns the ErrMissingBlock err carries, if any, locating the
+// missing block at the prefix of segments ending at index i, resolved from
+// the node from. i is -1 for a block needed by from itself.
+func (w *walker) missingBlock(err error, from ResolvedSegment, segments []string, i int) (ErrMissingBlock, bool) {
+	var missing ErrMissingBlock
+	if !errors.As(err, &missing) {
+		return missing, false
+	}
+	missing.Prefix = w.prefix(from, segments[:i+1])
+	missing.Remaining = len(segments) - i - 1
+	return missing, true
+}
@@ -0,0 +1,50 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	merkledag "github.com/ipfs/go-merkledag"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineFetcherFactory(t *testing.T) {
+	ctx := context.Background()
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	require.NoError(t, b.AddNodeLink("c", c))
+	require.NoError(t, a.AddNodeLink("b", b))
+	for _, n := range []*merkledag.ProtoNode{a, c} {
+		require.NoError(t, bs.Put(ctx, n))
+	}
+	r := resolver.NewBasicResolver(resolver.NewOfflineFetcherFactory(bs))
+	root := "/ipfs/" + a.Cid().String()
+
+	last, rest, err := r.ResolveToLastNode(ctx, path.FromString(root))
+	require.NoError(t, err)
+	require.Equal(t, a.Cid(), last)
+	require.Empty(t, rest)
+
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString(root+"/b/c"))
+	var missing resolver.ErrMissingBlock
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, b.Cid(), missing.Cid)
+	require.Equal(t, path.FromString(root+"/b"), missing.Prefix)
+	require.Equal(t, 1, missing.Remaining)
+	require.EqualError(t, err, "block "+b.Cid().String()+" is not available locally, needed to resolve "+root+"/b (1 segments remaining)")
+
+	// the root of the path
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString("/ipfs/"+b.Cid().String()+"/c"))
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, b.Cid(), missing.Cid)
+	require.Equal(t, path.FromString("/ipfs/"+b.Cid().String()), missing.Prefix)
+	require.Equal(t, 1, missing.Remaining)
+}
@@ -0,0 +1,142 @@
+package resolver
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTimeout is the overall time limit applied to a single resolution
+// when no other timeout is configured.
+const DefaultTimeout = time.Minute
+
+// Option configures a resolver constructed by NewBasicResolver.
+type Option func(*options)
+
+type options struct {
+	timeout        time.Duration
+	blockTimeout   time.Duration
+	callerDeadline bool
+	budget         Budget
+	index          *Index
+
+	noLinkNames       int
+	noLinkShardBlocks int
+
+	nameResolver       NameResolver
+	nameRecursionLimit int
+
+	clock Clock
+}
+
+func defaultOptions() options {
+	return options{
+		timeout:            DefaultTimeout,
+		nameRecursionLimit: DefaultNameRecursionLimit,
+		clock:              realClock{},
+	}
+}
+
+// WithTimeout bounds the total time a single resolution may take, from the
+// first block fetched to the last. A zero duration disables the limit.
+// Defaults to DefaultTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithBlockTimeout bounds the time spent fetching each individual block
+// while resolving a path. A zero duration, the default, disables the limit.
+func WithBlockTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.blockTimeout = d
+	}
+}
+
+// WithCallerDeadline lets a deadline set on the caller's context alone
+// decide how long a resolution may take: when the context passed to a
+// resolution method has a deadline, neither the resolver timeout nor the
+// block timeout is applied. Contexts without a deadline are still bounded by
+// the configured timeouts.
+func WithCallerDeadline() Option {
+	return func(o *options) {
+		o.callerDeadline = true
+	}
+}
+
+// WithBudget limits the resources every resolution may use. Budgets can
+// also be set for a single call with ContextWithBudget.
+func WithBudget(b Budget) Option {
+	return func(o *options) {
+		o.budget = b
+	}
+}
+
+// WithIndex makes ResolveToLastNode consult idx before fetching any block,
+// and record in it every path prefix it resolves, as does the
+// ResolveToLastNode method of a CachingResolver wrapping the resolver. The
+// other methods resolve every node of the path and do not use idx.
+func WithIndex(idx *Index) Option {
+	return func(o *options) {
+		o.index = idx
+	}
+}
+
+// WithNoLinkDetails makes ErrNoLink errors describe the node missing the
+// link (see NoLinkDetails): the path to it, up to maxNames of its names, and
+// the one closest to the missing name. Listing the names of a HAMT-sharded
+// directory fetches at most maxShardBlocks more blocks of the directory.
+// Details are disabled when maxNames is zero, the default.
+func WithNoLinkDetails(maxNames, maxShardBlocks int) Option {
+	return func(o *options) {
+		o.noLinkNames = maxNames
+		o.noLinkShardBlocks = maxShardBlocks
+	}
+}
+
+// WithNameResolver makes the resolver resolve /ipns/ paths, using nr to
+// resolve the name at their root. Resolutions are cached for their TTL.
+func WithNameResolver(nr NameResolver) Option {
+	return func(o *options) {
+		o.nameResolver = nr
+	}
+}
+
+// WithNameRecursionLimit sets the maximum number of names resolved for a
+// single path when names point to other names. It defaults to
+// DefaultNameRecursionLimit.
+func WithNameRecursionLimit(n int) Option {
+	return func(o *options) {
+		o.nameRecursionLimit = n
+	}
+}
+
+// WithClock sets the clock used to expire cached names and to schedule
+// Watch polls. It defaults to the system clock.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		if c != nil {
+			o.clock = c
+		}
+	}
+}
+
+// withTimeout applies the configured overall timeout to ctx and returns the
+// block timeout that applies to this resolution.
+func (o *options) withTimeout(ctx context.Context) (context.Context, context.CancelFunc, time.Duration) {
+	timeout, blockTimeout := o.timeout, o.blockTimeout
+	if _, ok := ctx.Deadline(); ok && o.callerDeadline {
+		timeout, blockTimeout = 0, 0
+	}
+
+	switch {
+	case timeout > 0:
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		return ctx, cancel, blockTimeout
+	case blockTimeout > 0:
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, blockTimeout
+	default:
+		return ctx, func() {}, 0
+	}
+}
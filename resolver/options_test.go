@@ -0,0 +1,172 @@
+package resolver_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-fetcher"
+	bsfetcher "github.com/ipfs/go-fetcher/impl/blockservice"
+	merkledag "github.com/ipfs/go-merkledag"
+	dagmock "github.com/ipfs/go-merkledag/test"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/ipfs/go-unixfsnode"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/stretchr/testify/require"
+)
+
+// slowFactory delays every block fetch of the sessions it creates. Like the
+// blockservice fetcher, its sessions only honor the session context.
+type slowFactory struct {
+	fetcher.Factory
+	delay time.Duration
+}
+
+func (f slowFactory) NewSession(ctx context.Context) fetcher.Fetcher {
+	return slowFetcher{Fetcher: f.Factory.NewSession(ctx), ctx: ctx, delay: f.delay}
+}
+
+type slowFetcher struct {
+	fetcher.Fetcher
+	ctx   context.Context
+	delay time.Duration
+}
+
+func (f slowFetcher) BlockOfType(ctx context.Context, lnk ipld.Link, np ipld.NodePrototype) (ipld.Node, error) {
+	select {
+	case <-time.After(f.delay):
+	case <-f.ctx.Done():
+		return nil, f.ctx.Err()
+	}
+	return f.Fetcher.BlockOfType(ctx, lnk, np)
+}
+
+func unixfsFetcherFactory(t *testing.T, nodes ...*merkledag.ProtoNode) fetcher.Factory {
+	t.Helper()
+	bsrv := dagmock.Bserv()
+	for _, n := range nodes {
+		require.NoError(t, bsrv.AddBlock(context.Background(), n))
+	}
+	fetcherFactory := resolver.NewFetcherFactory(bsrv)
+	fetcherFactory.PrototypeChooser = dagpb.AddSupportToChooser(bsfetcher.DefaultPrototypeChooser)
+	fetcherFactory.NodeReifier = unixfsnode.Reify
+	return fetcherFactory
+}
+
+func TestBlockTimeout(t *testing.T) {
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("child", b))
+	p := path.FromCid(a.Cid()).String() + "/child"
+
+	factory := slowFactory{Factory: unixfsFetcherFactory(t, a, b), delay: time.Second}
+	r := resolver.NewBasicResolver(factory, resolver.WithBlockTimeout(10*time.Millisecond))
+
+	start := time.Now()
+	_, _, err := r.ResolvePath(context.Background(), path.FromString(p))
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+	require.Less(t, time.Since(start), time.Second)
+
+	_, err = r.ResolvePathComponents(context.Background(), path.FromString(p))
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+
+	// a per-block timeout longer than the delay lets every block through
+	r = resolver.NewBasicResolver(slowFactory{Factory: factory.Factory, delay: time.Millisecond}, resolver.WithBlockTimeout(time.Second))
+	c, _, err := r.ResolveToLastNode(context.Background(), path.FromString(p))
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), c)
+}
+
+func TestTimeoutAppliesToAllMethods(t *testing.T) {
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("child", b))
+	p := path.FromString(path.FromCid(a.Cid()).String() + "/child")
+
+	factory := slowFactory{Factory: unixfsFetcherFactory(t, a, b), delay: 50 * time.Millisecond}
+	r := resolver.NewBasicResolver(factory, resolver.WithTimeout(20*time.Millisecond))
+
+	_, _, err := r.ResolveToLastNode(context.Background(), p)
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+	_, _, err = r.ResolvePath(context.Background(), p)
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+	_, err = r.ResolvePathComponents(context.Background(), p)
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+
+	// without a timeout the slow fetcher eventually succeeds
+	r = resolver.NewBasicResolver(factory, resolver.WithTimeout(0))
+	nodes, err := r.ResolvePathComponents(context.Background(), p)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+}
+
+func TestCallerDeadline(t *testing.T) {
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("child", b))
+	p := path.FromString(path.FromCid(a.Cid()).String() + "/child")
+
+	factory := slowFactory{Factory: unixfsFetcherFactory(t, a, b), delay: 50 * time.Millisecond}
+	r := resolver.NewBasicResolver(factory,
+		resolver.WithTimeout(20*time.Millisecond),
+		resolver.WithBlockTimeout(10*time.Millisecond),
+		resolver.WithCallerDeadline(),
+	)
+
+	// the caller's deadline replaces the resolver timeouts
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _, err := r.ResolvePath(ctx, p)
+	require.NoError(t, err)
+
+	// without a caller deadline, the resolver timeouts still apply
+	_, _, err = r.ResolvePath(context.Background(), p)
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+}
+
+// sessionContextFactory records the context of the sessions it creates.
+type sessionContextFactory struct {
+	fetcher.Factory
+	ctxs []context.Context
+}
+
+func (f *sessionContextFactory) NewSession(ctx context.Context) fetcher.Fetcher {
+	f.ctxs = append(f.ctxs, ctx)
+	return f.Factory.NewSession(ctx)
+}
+
+func TestTimeoutLeavesSessionToNodes(t *testing.T) {
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("child", b))
+	p := path.FromString(path.FromCid(a.Cid()).String() + "/child")
+
+	factory := &sessionContextFactory{Factory: unixfsFetcherFactory(t, a, b)}
+	r := resolver.NewBasicResolver(factory, resolver.WithTimeout(10*time.Millisecond))
+
+	// the session of returned nodes outlives the resolver timeout
+	_, _, err := r.ResolvePath(context.Background(), p)
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	require.Len(t, factory.ctxs, 1)
+	require.NoError(t, factory.ctxs[0].Err())
+
+	// and runs with the caller's context, derived from nothing the resolver
+	// would have to cancel
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = r.ResolvePathComponents(ctx, p)
+	require.NoError(t, err)
+	require.Len(t, factory.ctxs, 2)
+	require.True(t, factory.ctxs[1].Done() == ctx.Done())
+	cancel()
+	require.Error(t, factory.ctxs[1].Err())
+
+	// methods returning no node end their session
+	_, _, err = r.ResolveToLastNode(context.Background(), p)
+	require.NoError(t, err)
+	require.Len(t, factory.ctxs, 3)
+	require.Error(t, factory.ctxs[2].Err())
+}
@@ -0,0 +1,193 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-fetcher"
+	fetcherhelpers "github.com/ipfs/go-fetcher/helpers"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/internal"
+	"github.com/ipfs/go-path/internal/car"
+	"github.com/ipfs/go-unixfsnode"
+	"github.com/ipfs/go-unixfsnode/data"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal/selector"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
+)
+
+// DagScope selects which blocks below the terminal element of a path are
+// included in a proof, following the dag-scope parameter of the trustless
+// gateway specification.
+type DagScope string
+
+const (
+	// DagScopeBlock includes only the blocks needed to reach the terminal
+	// element of the path.
+	DagScopeBlock DagScope = "block"
+	// DagScopeEntity also includes the blocks forming the terminal entity:
+	// every block of a UnixFS file, or every shard of a HAMT-sharded
+	// directory. Other entities are made of a single block.
+	DagScopeEntity DagScope = "entity"
+	// DagScopeAll also includes every block reachable from the terminal
+	// element.
+	DagScopeAll DagScope = "all"
+)
+
+// matchAllSelector matches every node reachable from the node it is applied
+// to, crossing block boundaries.
+var matchAllSelector ipld.Node
+
+func init() {
+	ssb := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any)
+	matchAllSelector = ssb.ExploreRecursive(selector.RecursionLimitNone(), ssb.ExploreUnion(
+		ssb.Matcher(),
+		ssb.ExploreAll(ssb.ExploreRecursiveEdge()),
+	)).Node()
+}
+
+// WriteCAR resolves fpath, reading blocks from bg, and writes a proof of the
+// resolution to w as a CARv1 stream rooted at the root cid of the path. The
+// stream holds every block loaded to resolve the path, including the shards
+// of HAMT-sharded directories, in traversal order, followed by the blocks
+// below the terminal element selected by scope.
+//
+// /ipfs/ paths are resolved with UnixFS pathing, and /ipld/ paths with the
+// raw IPLD data model. The options configure the resolution as they do for
+// NewBasicResolver.
+func WriteCAR(ctx context.Context, w io.Writer, bg BlockGetter, fpath path.Path, scope DagScope, opts ...Option) error {
+	ctx, span := internal.StartSpan(ctx, "WriteCAR", trace.WithAttributes(attribute.Stringer("Path", fpath), attribute.String("Scope", string(scope))))
+	defer span.End()
+
+	switch scope {
+	case DagScopeBlock, DagScopeEntity, DagScopeAll:
+	default:
+		return fmt.Errorf("unknown dag scope %q", scope)
+	}
+
+	// validate path
+	if err := fpath.IsValid(); err != nil {
+		return err
+	}
+
+	c, p, err := path.SplitAbsPath(fpath)
+	if err != nil {
+		return err
+	}
+
+	cw, err := car.NewWriter(w, []cid.Cid{c})
+	if err != nil {
+		return err
+	}
+	written := cid.NewSet()
+	_, err = resolveProof(ctx, bg, func(blk blocks.Block) error {
+		if !written.Visit(blk.Cid()) {
+			return nil
+		}
+		return cw.WriteBlock(blk)
+	}, pathNamespace(fpath, nil), c, p, scope, opts)
+	return err
+}
+
+// resolveProof resolves the path made of root and segments with the pathing
+// of the namespace ns, reading blocks from bg, and then loads the blocks selected by
+// scope below its terminal element. Every block loaded is reported to
+// onBlock.
+func resolveProof(ctx context.Context, bg BlockGetter, onBlock func(blocks.Block) error, ns string, root cid.Cid, segments []string, scope DagScope, opts []Option) (*ResolveResult, error) {
+	factory := newBlockGetterFetcher(bg, onBlock)
+	factory.NodeReifier = unixfsnode.Reify
+
+	r := NewBasicResolver(factory, opts...).(*basicResolver)
+	ctx, w := r.newWalker(ctx, ns)
+	defer w.close()
+
+	res, err := r.resolve(ctx, w, root, segments, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// blocks below the terminal element are loaded without reification, so
+	// that they are read as stored
+	session := factory.WithReifier(nil).NewSession(ctx)
+	if err := loadScope(ctx, session, res, scope); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// loadScope loads the blocks selected by scope below the terminal element of
+// res.
+func loadScope(ctx context.Context, session fetcher.Fetcher, res *ResolveResult, scope DagScope) error {
+	last := res.Last()
+	switch scope {
+	case DagScopeAll:
+		visit := func(fetcher.FetchResult) error { return nil }
+		if len(res.Remainder) > 0 {
+			return session.NodeMatching(ctx, last.Node, matchAllSelector, visit)
+		}
+		return fetcherhelpers.BlockAll(ctx, session, cidlink.Link{Cid: last.Block}, visit)
+	case DagScopeEntity:
+		if len(res.Remainder) > 0 {
+			// the terminal element is within a block
+			return nil
+		}
+		return loadEntity(ctx, session, last.Block)
+	default:
+		return nil
+	}
+}
+
+// loadEntity loads the blocks of the UnixFS entity rooted at c. Only files
+// and HAMT-sharded directories span several blocks.
+func loadEntity(ctx context.Context, session fetcher.Fetcher, c cid.Cid) error {
+	if c.Prefix().Codec != cid.DagProtobuf {
+		return nil
+	}
+	nd, err := session.BlockOfType(ctx, cidlink.Link{Cid: c}, dagpb.Type.PBNode)
+	if err != nil {
+		return err
+	}
+	pbNode, ok := nd.(dagpb.PBNode)
+	if !ok || !pbNode.FieldData().Exists() {
+		return nil
+	}
+	ufsData, err := data.DecodeUnixFSData(pbNode.FieldData().Must().Bytes())
+	if err != nil {
+		// not UnixFS
+		return nil
+	}
+
+	switch ufsData.FieldDataType().Int() {
+	case data.Data_File, data.Data_Raw:
+		return fetcherhelpers.BlockAll(ctx, session, cidlink.Link{Cid: c}, func(fetcher.FetchResult) error { return nil })
+	case data.Data_HAMTShard:
+		if !ufsData.FieldFanout().Exists() {
+			return fmt.Errorf("HAMT shard %s has no fanout", c)
+		}
+		// links named with just the bucket prefix point to child shards
+		padLen := len(fmt.Sprintf("%X", ufsData.FieldFanout().Must().Int()-1))
+		for itr := pbNode.FieldLinks().Iterator(); !itr.Done(); {
+			_, lnk := itr.Next()
+			if !lnk.FieldName().Exists() || len(lnk.FieldName().Must().String()) != padLen {
+				continue
+			}
+			child, ok := lnk.FieldHash().Link().(cidlink.Link)
+			if !ok {
+				continue
+			}
+			if err := loadEntity(ctx, session, child.Cid); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
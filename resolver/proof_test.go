@@ -0,0 +1,103 @@
+package resolver_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	merkledag "github.com/ipfs/go-merkledag"
+	dagmock "github.com/ipfs/go-merkledag/test"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/internal/car"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/ipfs/go-unixfsnode/data"
+	"github.com/ipfs/go-unixfsnode/data/builder"
+	"github.com/stretchr/testify/require"
+)
+
+// readCAR returns the roots and the cids of the blocks of a CARv1 stream.
+func readCAR(t *testing.T, r io.Reader) ([]cid.Cid, []cid.Cid) {
+	t.Helper()
+	cr, err := car.NewReader(r)
+	require.NoError(t, err)
+	var cids []cid.Cid
+	for {
+		blk, err := cr.Next()
+		if err == io.EOF {
+			return cr.Roots, cids
+		}
+		require.NoError(t, err)
+		cids = append(cids, blk.Cid())
+	}
+}
+
+func TestWriteCAR(t *testing.T) {
+	ctx := context.Background()
+	bsrv := dagmock.Bserv()
+
+	leaf1 := randNode()
+	leaf2 := randNode()
+	ufs, err := builder.BuildUnixFS(func(b *builder.Builder) {
+		builder.DataType(b, data.Data_File)
+		builder.FileSize(b, uint64(len(leaf1.Data())+len(leaf2.Data())))
+	})
+	require.NoError(t, err)
+	file := new(merkledag.ProtoNode)
+	file.SetData(data.EncodeUnixFSData(ufs))
+	require.NoError(t, file.AddNodeLink("", leaf1))
+	require.NoError(t, file.AddNodeLink("", leaf2))
+
+	other := randNode()
+	root := randNode()
+	require.NoError(t, root.AddNodeLink("file", file))
+	require.NoError(t, root.AddNodeLink("other", other))
+
+	for _, n := range []*merkledag.ProtoNode{leaf1, leaf2, file, other, root} {
+		require.NoError(t, bsrv.AddBlock(ctx, n))
+	}
+
+	cbor := cborBlock(t, `{"a": {"b": {"/": "`+other.Cid().String()+`"}}}`)
+	require.NoError(t, bsrv.AddBlock(ctx, cbor))
+
+	filePath := path.FromCid(root.Cid()).String() + "/file"
+	cborPath := path.FromCid(cbor.Cid()).String() + "/a"
+
+	testCases := []struct {
+		name   string
+		path   string
+		scope  resolver.DagScope
+		root   cid.Cid
+		blocks []cid.Cid
+	}{
+		{"block", filePath, resolver.DagScopeBlock, root.Cid(), []cid.Cid{root.Cid(), file.Cid()}},
+		{"entity", filePath, resolver.DagScopeEntity, root.Cid(), []cid.Cid{root.Cid(), file.Cid(), leaf1.Cid(), leaf2.Cid()}},
+		{"all", filePath, resolver.DagScopeAll, root.Cid(), []cid.Cid{root.Cid(), file.Cid(), leaf1.Cid(), leaf2.Cid()}},
+		{"entity of non-file", path.FromCid(root.Cid()).String(), resolver.DagScopeEntity, root.Cid(), []cid.Cid{root.Cid()}},
+		{"block within block", cborPath, resolver.DagScopeBlock, cbor.Cid(), []cid.Cid{cbor.Cid()}},
+		{"entity within block", cborPath, resolver.DagScopeEntity, cbor.Cid(), []cid.Cid{cbor.Cid()}},
+		{"all within block", cborPath, resolver.DagScopeAll, cbor.Cid(), []cid.Cid{cbor.Cid(), other.Cid()}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, resolver.WriteCAR(ctx, &buf, bsrv, path.FromString(tc.path), tc.scope))
+			roots, blocks := readCAR(t, &buf)
+			require.Equal(t, []cid.Cid{tc.root}, roots)
+			require.Equal(t, tc.blocks, blocks)
+		})
+	}
+
+	t.Run("missing link", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := resolver.WriteCAR(ctx, &buf, bsrv, path.FromString(path.FromCid(root.Cid()).String()+"/missing"), resolver.DagScopeBlock)
+		require.ErrorAs(t, err, &resolver.ErrNoLink{})
+	})
+
+	t.Run("unknown scope", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := resolver.WriteCAR(ctx, &buf, bsrv, path.FromString(filePath), "subtree")
+		require.EqualError(t, err, `unknown dag scope "subtree"`)
+	})
+}
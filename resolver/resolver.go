@@ -5,23 +5,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 
 	cid "github.com/ipfs/go-cid"
 	"github.com/ipfs/go-fetcher"
-	fetcherhelpers "github.com/ipfs/go-fetcher/helpers"
+	bsfetcher "github.com/ipfs/go-fetcher/impl/blockservice"
 	format "github.com/ipfs/go-ipld-format"
 	logging "github.com/ipfs/go-log"
 	path "github.com/ipfs/go-path"
 	"github.com/ipfs/go-path/internal"
+	"github.com/ipfs/go-unixfsnode"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
-	basicnode "github.com/ipld/go-ipld-prime/node/basic"
 	"github.com/ipld/go-ipld-prime/schema"
-	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
 )
 
 var log = logging.Logger("pathresolv")
@@ -39,16 +37,38 @@ var ErrNoComponents = errors.New(
 type ErrNoLink struct {
 	Name string
 	Node cid.Cid
+	// Details describes the node missing the link, when enabled with
+	// WithNoLinkDetails.
+	Details *NoLinkDetails
 }
 
 // Error implements the Error interface for ErrNoLink with a useful
 // human readable message.
 func (e ErrNoLink) Error() string {
-	return fmt.Sprintf("no link named %q under %s", e.Name, e.Node.String())
+	msg := fmt.Sprintf("no link named %q under %s", e.Name, e.Node.String())
+	if e.Details == nil {
+		return msg
+	}
+	if e.Details.Parent != "" {
+		msg += fmt.Sprintf(" at %s", e.Details.Parent)
+	}
+	if e.Details.Suggestion != "" {
+		msg += fmt.Sprintf("; did you mean %q?", e.Details.Suggestion)
+	}
+	return msg
 }
 
 // Resolver provides path resolution to IPFS.
 //
+// Every resolution fetches blocks through a session of its own, unless the
+// resolver shares one (see SessionResolver).
+//
+// The functions of this package taking a Resolver, such as Resolve and
+// Watch, use the method of the same name of the resolver, which resolvers
+// may implement beyond this interface. The resolvers returned by
+// NewBasicResolver and NewCachingResolver implement all of them; with other
+// resolvers, the functions fail with ErrUnsupported.
+//
 // Deprecated: use github.com/ipfs/boxo/path/resolver.Resolver
 type Resolver interface {
 	// ResolveToLastNode walks the given path and returns the cid of the
@@ -62,10 +82,24 @@ type Resolver interface {
 	ResolvePath(ctx context.Context, fpath path.Path) (ipld.Node, ipld.Link, error)
 	// ResolvePathComponents fetches the nodes for each segment of the given path.
 	// It uses the first path component as a hash (key) of the first node, then
-	// resolves all other components walking the links via a selector traversal
+	// resolves all other components walking the links from node to node.
 	ResolvePathComponents(ctx context.Context, fpath path.Path) ([]ipld.Node, error)
 }
 
+// LinkResolver is a Resolver also resolving names relative to a node the
+// caller already holds. The resolvers returned by NewBasicResolver and
+// NewCachingResolver are LinkResolvers.
+type LinkResolver interface {
+	Resolver
+	// ResolveLinks iteratively resolves names by walking the link
+	// hierarchy from ndd. It returns the list of nodes forming the path,
+	// starting with ndd, which is never empty. Names that do not resolve
+	// end the list early rather than failing.
+	ResolveLinks(ctx context.Context, ndd ipld.Node, names []string) ([]ipld.Node, error)
+}
+
+var _ LinkResolver = (*basicResolver)(nil)
+
 // basicResolver implements the Resolver interface.
 // It references a FetcherFactory, which is uses to resolve nodes.
 // TODO: now that this is more modular, try to unify this code with the
@@ -73,25 +107,58 @@ type Resolver interface {
 //	the resolvers in namesys.
 type basicResolver struct {
 	FetcherFactory fetcher.Factory
+
+	opts  options
+	names *nameCache
+	// session is the session shared by every resolution, if any.
+	session *fetchSession
 }
 
 // NewBasicResolver constructs a new basic resolver.
 //
 // Deprecated: use github.com/ipfs/boxo/path/resolver.NewBasicResolver
-func NewBasicResolver(fetcherFactory fetcher.Factory) Resolver {
-	return &basicResolver{
+func NewBasicResolver(fetcherFactory fetcher.Factory, opts ...Option) Resolver {
+	r := &basicResolver{
 		FetcherFactory: fetcherFactory,
+		opts:           defaultOptions(),
+	}
+	for _, opt := range opts {
+		opt(&r.opts)
 	}
+	if r.opts.nameResolver != nil {
+		r.names = newNameCache(r.opts.clock)
+	}
+	return r
+}
+
+// ErrUnsupported is returned by the functions of this package taking a
+// Resolver when the resolver does not implement the method they use.
+type ErrUnsupported struct {
+	// Resolver is the resolver, as given.
+	Resolver Resolver
+	// Method is the name of the missing method.
+	Method string
+}
+
+// Error implements the Error interface for ErrUnsupported with a useful
+// human readable message.
+func (e ErrUnsupported) Error() string {
+	return fmt.Sprintf("resolver %T does not support %s", e.Resolver, e.Method)
 }
 
 // ResolveToLastNode walks the given path and returns the cid of the last
 // block referenced by the path, and the path segments to traverse from the
-// final block boundary to the final node within the block.
+// final block boundary to the final node within the block. If the path fails
+// to resolve after its root was loaded, the error is an ErrPartialResolution
+// (see the Resolve function).
 func (r *basicResolver) ResolveToLastNode(ctx context.Context, fpath path.Path) (cid.Cid, []string, error) {
 	ctx, span := internal.StartSpan(ctx, "basicResolver.ResolveToLastNode", trace.WithAttributes(attribute.Stringer("Path", fpath)))
 	defer span.End()
 
-	c, p, err := path.SplitAbsPath(fpath)
+	// the names at the root are resolved within the resolution scope too
+	ctx, w := r.newWalker(ctx, "")
+	defer w.close()
+	c, p, _, err := r.resolveRoot(ctx, w, fpath)
 	if err != nil {
 		return cid.Cid{}, nil, err
 	}
@@ -100,45 +167,88 @@ func (r *basicResolver) ResolveToLastNode(ctx context.Context, fpath path.Path)
 		return c, nil, nil
 	}
 
-	// create a selector to traverse and match all path segments
-	pathSelector := pathAllSelector(p[:len(p)-1])
-
-	// create a new cancellable session
-	ctx, cancel := context.WithTimeout(ctx, time.Minute)
-	defer cancel()
+	// the index holds UnixFS resolutions
+	if r.opts.index != nil && w.ns == nsIPFS {
+		return r.resolveIndexed(ctx, w, fpath, c, p)
+	}
 
-	// resolve node before last path segment
-	nodes, lastCid, depth, err := r.resolveNodes(ctx, c, pathSelector)
+	// resolve all segments, without loading a link found under the last one
+	res, err := r.resolve(ctx, w, c, p, false)
 	if err != nil {
+		if res != nil {
+			return cid.Cid{}, nil, partialResolution(w.ns, res, err)
+		}
 		return cid.Cid{}, nil, err
 	}
+	return lastNode(fpath, res.Last(), res.Remainder)
+}
 
-	if len(nodes) < 1 {
-		return cid.Cid{}, nil, fmt.Errorf("path %v did not resolve to a node", fpath)
-	} else if len(nodes) < len(p) {
-		return cid.Undef, nil, ErrNoLink{Name: p[len(nodes)-1], Node: lastCid}
+// resolveIndexed is ResolveToLastNode resolving only the segments after the
+// longest prefix of the path found in the index, and indexing the prefixes it
+// resolves.
+func (r *basicResolver) resolveIndexed(ctx context.Context, w *walker, fpath path.Path, c cid.Cid, p []string) (cid.Cid, []string, error) {
+	if err := w.checkDepth(p); err != nil {
+		return cid.Cid{}, nil, err
 	}
 
-	parent := nodes[len(nodes)-1]
-	lastSegment := p[len(p)-1]
+	start, from, err := r.opts.index.lookup(ctx, c, p)
+	if err != nil {
+		log.Warnf("could not look up %s in the path index: %s", fpath, err)
+		from = 0
+	}
+	if from == len(p) {
+		return start.c, start.rest, nil
+	}
+	if from == 0 {
+		start = indexEntry{c: c}
+	}
 
-	// find final path segment within node
-	nd, err := parent.LookupBySegment(ipld.ParsePathSegment(lastSegment))
-	switch err.(type) {
-	case nil:
-	case schema.ErrNoSuchField:
-		return cid.Undef, nil, ErrNoLink{Name: lastSegment, Node: lastCid}
-	default:
-		return cid.Cid{}, nil, err
+	// resume from the indexed prefix
+	res, prefixes, err := r.resume(ctx, w, fpath, start, p, from)
+	if err != nil {
+		return cid.Cid{}, nil, resumedPartialResolution(w.ns, c, p, from-len(start.rest), res, err)
+	}
+	if err := r.opts.index.add(ctx, c, p, from, prefixes); err != nil {
+		log.Warnf("could not add %s to the path index: %s", fpath, err)
 	}
 
+	return lastNode(fpath, res.Last(), res.Remainder)
+}
+
+// resume resolves the segments p[from:] of fpath from start, the resolution
+// of the prefix p[:from], without loading a link found under the last one.
+// It also returns the resolution of every longer prefix resolved, in order,
+// up to the segment that failed to resolve, if any.
+func (r *basicResolver) resume(ctx context.Context, w *walker, fpath path.Path, start indexEntry, p []string, from int) (*ResolveResult, []indexEntry, error) {
+	segments := append(append([]string{}, start.rest...), p[from:]...)
+	w.skipped = p[:from-len(start.rest)]
+	res, err := r.resolve(ctx, w, start.c, segments, false)
+	if res == nil {
+		return nil, nil, err
+	}
+
+	prefixes := make([]indexEntry, 0, len(p)-from)
+	for i := len(start.rest); i < len(res.Segments); i++ {
+		lc, rest, err := lastNode(fpath, res.Segments[i], remainder(res.Segments[:i+1]))
+		if err != nil {
+			break
+		}
+		prefixes = append(prefixes, indexEntry{c: lc, rest: rest})
+	}
+	return res, prefixes, err
+}
+
+// lastNode returns the result of ResolveToLastNode for a path resolving to
+// last, with rest the segments resolved within its block: the cid of the
+// block and rest, or the cid of the block last links to.
+func lastNode(fpath path.Path, last ResolvedSegment, rest []string) (cid.Cid, []string, error) {
 	// if last node is not a link, just return it's cid, add path to remainder and return
-	if nd.Kind() != ipld.Kind_Link {
+	if last.Node.Kind() != ipld.Kind_Link {
 		// return the cid and the remainder of the path
-		return lastCid, p[len(p)-depth-1:], nil
+		return last.Block, rest, nil
 	}
 
-	lnk, err := nd.AsLink()
+	lnk, err := last.Node.AsLink()
 	if err != nil {
 		return cid.Cid{}, nil, err
 	}
@@ -165,27 +275,88 @@ func (r *basicResolver) ResolvePath(ctx context.Context, fpath path.Path) (ipld.
 		return nil, nil, err
 	}
 
-	c, p, err := path.SplitAbsPath(fpath)
+	ctx, w := r.newNodeWalker(ctx, "")
+	defer w.close()
+	c, p, _, err := r.resolveRoot(ctx, w, fpath)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// create a selector to traverse all path segments but only match the last
-	pathSelector := pathLeafSelector(p)
-
-	nodes, c, _, err := r.resolveNodes(ctx, c, pathSelector)
+	res, err := r.resolve(ctx, w, c, p, true)
 	if err != nil {
+		switch err.(type) {
+		case ErrNoLink, ErrNotTraversable:
+			return nil, nil, fmt.Errorf("path %v did not resolve to a node: %w", fpath, err)
+		}
 		return nil, nil, err
 	}
-	if len(nodes) < 1 {
-		return nil, nil, fmt.Errorf("path %v did not resolve to a node", fpath)
+	last := res.Last()
+	return last.Node, cidlink.Link{Cid: last.Block}, nil
+}
+
+// Resolve walks the given path with r and returns the resolution of every
+// segment: its node, the block containing it and whether a block boundary
+// was crossed to reach it. If the path fails to resolve after its root was
+// loaded, the error is an ErrPartialResolution holding what was resolved.
+//
+// r must have a method Resolve(ctx, fpath), as the resolvers of this package
+// do.
+//
+// Note: if/when the context is cancelled or expires then if a multi-block ADL node is returned then it may not be
+// possible to load certain values.
+func Resolve(ctx context.Context, r Resolver, fpath path.Path) (*ResolveResult, error) {
+	rr, ok := r.(interface {
+		Resolve(context.Context, path.Path) (*ResolveResult, error)
+	})
+	if !ok {
+		return nil, ErrUnsupported{Resolver: r, Method: "Resolve"}
 	}
-	return nodes[len(nodes)-1], cidlink.Link{Cid: c}, nil
+	return rr.Resolve(ctx, fpath)
+}
+
+// Resolve walks the given path and returns the resolution of every segment
+// (see the Resolve function).
+func (r *basicResolver) Resolve(ctx context.Context, fpath path.Path) (*ResolveResult, error) {
+	ctx, span := internal.StartSpan(ctx, "basicResolver.Resolve", trace.WithAttributes(attribute.Stringer("Path", fpath)))
+	defer span.End()
+
+	// validate path
+	if err := fpath.IsValid(); err != nil {
+		return nil, err
+	}
+
+	ctx, w := r.newNodeWalker(ctx, "")
+	defer w.close()
+	c, p, hops, err := r.resolveRoot(ctx, w, fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.resolve(ctx, w, c, p, true)
+	if err != nil {
+		if res != nil {
+			res.Names = hops
+			return nil, partialResolution(w.ns, res, err)
+		}
+		return nil, err
+	}
+	res.Names = hops
+	return res, nil
+}
+
+// unmatched returns true if err reports a path segment that names nothing:
+// ResolvePathComponents and ResolveLinks return the nodes resolved before
+// such a segment, as they did when resolving with selectors, which matched
+// nothing more. A path into a node that cannot be traversed, such as a file,
+// is an error.
+func unmatched(err error) bool {
+	_, ok := err.(ErrNoLink)
+	return ok
 }
 
 // ResolveSingle simply resolves one hop of a path through a graph with no
 // extra context (does not opaquely resolve through sharded nodes)
-// Deprecated: fetch node as ipld-prime or convert it and then use a selector to traverse through it.
+// Deprecated: fetch node as ipld-prime or convert it and then use ResolveHop to traverse through it.
 //
 // Deprecated: use github.com/ipfs/boxo/path/resolver.ResolveSingle
 func ResolveSingle(ctx context.Context, ds format.NodeGetter, nd format.Node, names []string) (*format.Link, []string, error) {
@@ -194,9 +365,51 @@ func ResolveSingle(ctx context.Context, ds format.NodeGetter, nd format.Node, na
 	return nd.ResolveLink(names)
 }
 
+// ResolveHop resolves names from the node nd of the block c, without fetching
+// anything: it follows the names within the block and stops at the first
+// link. It returns that link, the names left to resolve from its target, and
+// the nodes each name resolved to, the link node included.
+//
+// If every name resolves within the block, the link is nil and no names are
+// left.
+func ResolveHop(ctx context.Context, c cid.Cid, nd ipld.Node, names []string) (ipld.Link, []string, []ipld.Node, error) {
+	_, span := internal.StartSpan(ctx, "ResolveHop", trace.WithAttributes(attribute.Stringer("CID", c)))
+	defer span.End()
+
+	nodes := make([]ipld.Node, 0, len(names))
+	cur := nd
+	for i, name := range names {
+		if what := notTraversable(ResolvedSegment{Node: cur, Block: c, Depth: i}, ""); what != "" {
+			return nil, nil, nodes, ErrNotTraversable{Segment: name, Kind: cur.Kind(), Block: c, What: what}
+		}
+
+		_, next, err := lookup(cur, name)
+		switch err.(type) {
+		case nil:
+		case ipld.ErrNotExists, schema.ErrNoSuchField:
+			return nil, nil, nodes, ErrNoLink{Name: name, Node: c}
+		default:
+			return nil, nil, nodes, err
+		}
+		nodes = append(nodes, next)
+
+		if next.Kind() == ipld.Kind_Link {
+			lnk, err := next.AsLink()
+			if err != nil {
+				return nil, nil, nodes, err
+			}
+			return lnk, names[i+1:], nodes, nil
+		}
+		cur = next
+	}
+	return nil, []string{}, nodes, nil
+}
+
 // ResolvePathComponents fetches the nodes for each segment of the given path.
 // It uses the first path component as a hash (key) of the first node, then
-// resolves all other components walking the links via a selector traversal
+// resolves all other components walking the links from node to node.
+// A path naming a missing entry resolves to the nodes found before it, while
+// a path into a node that cannot be traversed fails with ErrNotTraversable.
 //
 // Note: if/when the context is cancelled or expires then if a multi-block ADL node is returned then it may not be
 // possible to load certain values.
@@ -214,21 +427,22 @@ func (r *basicResolver) ResolvePathComponents(ctx context.Context, fpath path.Pa
 		return nil, err
 	}
 
-	c, p, err := path.SplitAbsPath(fpath)
+	ctx, w := r.newNodeWalker(ctx, "")
+	defer w.close()
+	c, p, _, err := r.resolveRoot(ctx, w, fpath)
 	if err != nil {
 		evt.Append(logging.LoggableMap{"error": err.Error()})
 		return nil, err
 	}
 
-	// create a selector to traverse and match all path segments
-	pathSelector := pathAllSelector(p)
-
-	nodes, _, _, err := r.resolveNodes(ctx, c, pathSelector)
-	if err != nil {
+	res, err := r.resolve(ctx, w, c, p, true)
+	if err != nil && !unmatched(err) {
 		evt.Append(logging.LoggableMap{"error": err.Error()})
+		return nil, err
 	}
 
-	return nodes, err
+	// a path stopping short of its last segment resolves to the nodes found
+	return res.Nodes(), nil
 }
 
 // ResolveLinks iteratively resolves names by walking the link hierarchy.
@@ -249,84 +463,176 @@ func (r *basicResolver) ResolveLinks(ctx context.Context, ndd ipld.Node, names [
 	evt := log.EventBegin(ctx, "resolveLinks", logging.LoggableMap{"names": names})
 	defer evt.Done()
 
-	// create a selector to traverse and match all path segments
-	pathSelector := pathAllSelector(names)
+	// the namespace of the path is unknown, use the factory as configured
+	ctx, w := r.newNodeWalker(ctx, "")
+	defer w.close()
 
-	session := r.FetcherFactory.NewSession(ctx)
-
-	// traverse selector
-	nodes := []ipld.Node{ndd}
-	err := session.NodeMatching(ctx, ndd, pathSelector, func(res fetcher.FetchResult) error {
-		nodes = append(nodes, res.Node)
-		return nil
-	})
-	if err != nil {
+	// walk all names starting from the given node
+	res := &ResolveResult{Root: ResolvedSegment{Node: ndd}}
+	segments, err := w.walk(ctx, res.Root, names, true)
+	if err != nil && !unmatched(err) {
 		evt.Append(logging.LoggableMap{"error": err.Error()})
 		return nil, err
 	}
+	res.Segments = segments
 
-	return nodes, err
+	return res.Nodes(), nil
 }
 
-// Finds nodes matching the selector starting with a cid. Returns the matched nodes, the cid of the block containing
-// the last node, and the depth of the last node within its block (root is depth 0).
-func (r *basicResolver) resolveNodes(ctx context.Context, c cid.Cid, sel ipld.Node) ([]ipld.Node, cid.Cid, int, error) {
-	ctx, span := internal.StartSpan(ctx, "basicResolver.resolveNodes", trace.WithAttributes(attribute.Stringer("CID", c)))
-	defer span.End()
-	session := r.FetcherFactory.NewSession(ctx)
-
-	// traverse selector
-	lastLink := cid.Undef
-	depth := 0
-	nodes := []ipld.Node{}
-	err := fetcherhelpers.BlockMatching(ctx, session, cidlink.Link{Cid: c}, sel, func(res fetcher.FetchResult) error {
-		if res.LastBlockLink == nil {
-			res.LastBlockLink = cidlink.Link{Cid: c}
-		}
-		cidLnk, ok := res.LastBlockLink.(cidlink.Link)
-		if !ok {
-			return fmt.Errorf("link is not a cidlink: %v", cidLnk)
-		}
+// ResolveLinks iteratively resolves names from ndd with r, walking the link
+// hierarchy (see LinkResolver). r must be a LinkResolver, as the resolvers
+// of this package are.
+func ResolveLinks(ctx context.Context, r Resolver, ndd ipld.Node, names []string) ([]ipld.Node, error) {
+	lr, ok := r.(LinkResolver)
+	if !ok {
+		return nil, ErrUnsupported{Resolver: r, Method: "ResolveLinks"}
+	}
+	return lr.ResolveLinks(ctx, ndd, names)
+}
 
-		// if we hit a block boundary
-		if !lastLink.Equals(cidLnk.Cid) {
-			depth = 0
-			lastLink = cidLnk.Cid
-		} else {
-			depth++
-		}
+// newWalker prepares a fetcher session, started when the first block is
+// fetched, and a resolution scope bounded by the resolver's timeouts. The
+// returned context is the scope: it ends when the walker is closed, when ctx
+// is done or when the resolver timeout elapses, whichever comes first. The
+// session ends when the walker is closed or ctx is done. Resolutions made
+// with the walker share the resolver budget and the one carried by ctx, if
+// any.
+//
+// Nodes are reified for pathing in the namespace ns (see reifierFor), which
+// resolveRoot sets once the names at the root of a path are resolved. If the
+// resolver shares a session (see SessionResolver), the walker uses it and
+// never ends it.
+func (r *basicResolver) newWalker(ctx context.Context, ns string) (context.Context, *walker) {
+	if r.session != nil {
+		return r.newNodeWalker(ctx, ns)
+	}
+	sessionCtx, endSession := context.WithCancel(ctx)
+	scope, w := r.scopedWalker(ctx, newFetchSession(sessionCtx, r.FetcherFactory), r.newBudget(ctx))
+	w.endSession = endSession
+	r.enterNamespace(w, ns)
+	return scope, w
+}
 
-		nodes = append(nodes, res.Node)
-		return nil
-	})
-	if err != nil {
-		return nil, cid.Undef, 0, err
+// newNodeWalker is newWalker for resolutions handing nodes to the caller:
+// their session runs until ctx is done, so that the nodes can load more
+// blocks after the walker is closed. Fetches given up on are left to the
+// session.
+func (r *basicResolver) newNodeWalker(ctx context.Context, ns string) (context.Context, *walker) {
+	session := r.session
+	if session == nil {
+		session = newFetchSession(ctx, r.FetcherFactory)
 	}
+	scope, w := r.scopedWalker(ctx, session, r.newBudget(ctx))
+	r.enterNamespace(w, ns)
+	return scope, w
+}
 
-	return nodes, lastLink, depth, nil
+// newBudget returns the tracker of the budget of a resolution with ctx: the
+// resolver budget merged with the one carried by ctx, if any.
+func (r *basicResolver) newBudget(ctx context.Context) *budgetTracker {
+	return &budgetTracker{Budget: r.opts.budget.merge(budgetFromContext(ctx))}
 }
 
-func pathLeafSelector(path []string) ipld.Node {
-	ssb := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any)
-	return pathSelector(path, ssb, func(p string, s builder.SelectorSpec) builder.SelectorSpec {
-		return ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) { efsb.Insert(p, s) })
-	})
+// subWalker returns a walker resolving through the session of w, sharing its
+// budget, within a scope of its own bounded by the resolver's timeouts like
+// the scope of a new walker. Closing it ends its scope only: fetches it gives
+// up on are left to the shared session, which w ends.
+func (r *basicResolver) subWalker(ctx context.Context, w *walker) (context.Context, *walker) {
+	scope, sw := r.scopedWalker(ctx, w.session, w.budget)
+	r.enterNamespace(sw, w.ns)
+	return scope, sw
 }
 
-func pathAllSelector(path []string) ipld.Node {
-	ssb := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any)
-	return pathSelector(path, ssb, func(p string, s builder.SelectorSpec) builder.SelectorSpec {
-		return ssb.ExploreUnion(
-			ssb.Matcher(),
-			ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) { efsb.Insert(p, s) }),
-		)
-	})
+// scopedWalker returns a walker resolving through session within a
+// resolution scope derived from ctx. The walker does not end session, and
+// has no namespace until enterNamespace is called.
+func (r *basicResolver) scopedWalker(ctx context.Context, session *fetchSession, budget *budgetTracker) (context.Context, *walker) {
+	scope, cancel, blockTimeout := r.opts.withTimeout(ctx)
+	w := &walker{
+		session:           session,
+		endSession:        func() {},
+		scope:             scope,
+		cancel:            cancel,
+		blockTimeout:      blockTimeout,
+		budget:            budget,
+		noLinkNames:       r.opts.noLinkNames,
+		noLinkShardBlocks: r.opts.noLinkShardBlocks,
+	}
+	return scope, w
+}
+
+// enterNamespace makes w resolve paths with the pathing of the namespace ns.
+func (r *basicResolver) enterNamespace(w *walker, ns string) {
+	w.ns = ns
+	w.reifier = r.reifierFor(ns)
+	if w.session.source != nil {
+		w.lsys = w.newLinkSystem()
+	}
+}
+
+// reifierFactory is a fetcher.Factory able to derive factories with other
+// NodeReifiers, such as the blockservice fetcher factory.
+type reifierFactory interface {
+	WithReifier(ipld.NodeReifier) fetcher.Factory
+}
+
+// reifierFor returns the NodeReifier for paths in the namespace ns: /ipfs/
+// paths are resolved with UnixFS pathing and /ipld/ paths with the raw IPLD
+// data model, while paths in an unknown namespace use the NodeReifier of the
+// fetcher factory, if known. Factories that cannot change their NodeReifier
+// apply their own to every path.
+func (r *basicResolver) reifierFor(ns string) ipld.NodeReifier {
+	switch ns {
+	case nsIPFS:
+		return unixfsnode.Reify
+	case nsIPLD:
+		return nil
+	}
+	switch f := r.FetcherFactory.(type) {
+	case FetcherFactory:
+		return f.NodeReifier
+	case bsfetcher.FetcherConfig:
+		return f.NodeReifier
+	}
+	return nil
 }
 
-func pathSelector(path []string, ssb builder.SelectorSpecBuilder, reduce func(string, builder.SelectorSpec) builder.SelectorSpec) ipld.Node {
-	spec := ssb.Matcher()
-	for i := len(path) - 1; i >= 0; i-- {
-		spec = reduce(path[i], spec)
+// Loads the block c and walks the given path segments from its root. On error, the result holds the segments
+// resolved before the failing one, or is nil if the root block could not be loaded.
+func (r *basicResolver) resolve(ctx context.Context, w *walker, c cid.Cid, segments []string, loadLast bool) (*ResolveResult, error) {
+	ctx, span := internal.StartSpan(ctx, "basicResolver.resolve", trace.WithAttributes(attribute.Stringer("CID", c)))
+	defer span.End()
+
+	if err := w.checkDepth(segments); err != nil {
+		return nil, err
+	}
+
+	w.atRoot()
+	var root ipld.Node
+	var err error
+	if w.rootPrototype != nil {
+		root, err = w.loadAs(ctx, cidlink.Link{Cid: c}, w.rootPrototype)
+	} else {
+		root, err = w.load(ctx, cidlink.Link{Cid: c}, nil)
+	}
+	if err != nil {
+		if missing, ok := w.missingBlock(err, ResolvedSegment{Block: c}, segments, -1); ok {
+			return nil, missing
+		}
+		return nil, err
+	}
+	if err := w.budget.visit(w.segment, w.index); err != nil {
+		return nil, err
+	}
+
+	res := &ResolveResult{Root: ResolvedSegment{Node: root, Block: c, Boundary: true, Inline: isInline(c)}}
+	if err := w.reached(ctx, res.Root); err != nil {
+		return res, err
+	}
+	res.Segments, err = w.walk(ctx, res.Root, segments, loadLast)
+	res.Remainder = remainder(res.Segments)
+	if err != nil {
+		return res, err
 	}
-	return spec.Node()
+	return res, nil
 }
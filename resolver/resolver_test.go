@@ -291,3 +291,112 @@ func TestResolveToLastNode_MixedSegmentTypes(t *testing.T) {
 	assert.Equal(t, 0, len(remainder))
 	assert.True(t, cid.Equals(a.Cid()))
 }
+
+func TestResolveToLastNode_ListIndexes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bsrv := dagmock.Bserv()
+	a := randNode()
+	require.NoError(t, bsrv.AddBlock(ctx, a))
+
+	json := `{"items":[{"name":"zero"},{"name":"one"},{"/":"CID"}],"3":{"link":{"/":"CID"}}}`
+	blk := cborBlock(t, strings.ReplaceAll(json, "CID", a.Cid().String()))
+	require.NoError(t, bsrv.AddBlock(ctx, blk))
+	lnk := blk.Cid()
+	r := resolver.NewBasicResolver(bsfetcher.NewFetcherConfig(bsrv))
+	root := "/ipld/" + lnk.String()
+
+	// numeric segments index lists
+	c, remainder, err := r.ResolveToLastNode(ctx, path.FromString(root+"/items/1/name"))
+	require.NoError(t, err)
+	require.Equal(t, lnk, c)
+	require.Equal(t, []string{"items", "1", "name"}, remainder)
+
+	c, remainder, err = r.ResolveToLastNode(ctx, path.FromString(root+"/items/2"))
+	require.NoError(t, err)
+	require.Equal(t, a.Cid(), c)
+	require.Empty(t, remainder)
+
+	// and are keys in maps
+	c, _, err = r.ResolveToLastNode(ctx, path.FromString(root+"/3/link"))
+	require.NoError(t, err)
+	require.Equal(t, a.Cid(), c)
+
+	// list indexes must be in range and in canonical form
+	for _, seg := range []string{"3", "-1", "01", "+1", "one"} {
+		_, _, err = r.ResolveToLastNode(ctx, path.FromString(root+"/items/"+seg))
+		require.ErrorAs(t, err, &resolver.ErrNoLink{}, seg)
+	}
+
+	// the resolution records typed segments
+	res, err := resolver.Resolve(ctx, r, path.FromString(root+"/items/1/name"))
+	require.NoError(t, err)
+	require.Equal(t, ipld.NewPath([]ipld.PathSegment{
+		ipld.PathSegmentOfString("items"),
+		ipld.PathSegmentOfInt(1),
+		ipld.PathSegmentOfString("name"),
+	}), res.Path())
+
+	p, err := path.FromIPLDPath("/ipld/", lnk, res.Path())
+	require.NoError(t, err)
+	require.Equal(t, path.FromString(root+"/items/1/name"), p)
+}
+
+func TestResolveHop(t *testing.T) {
+	ctx := context.Background()
+	a := randNode()
+	blk := cborBlock(t, `{"a":{"b":[0,{"/":"`+a.Cid().String()+`"}]},"c":"d"}`)
+	nb := basicnode.Prototype.Any.NewBuilder()
+	require.NoError(t, dagcbor.Decode(nb, bytes.NewReader(blk.RawData())))
+	nd := nb.Build()
+
+	// stops at the first link
+	lnk, rest, nodes, err := resolver.ResolveHop(ctx, blk.Cid(), nd, []string{"a", "b", "1", "x", "y"})
+	require.NoError(t, err)
+	require.Equal(t, cidlink.Link{Cid: a.Cid()}, lnk)
+	require.Equal(t, []string{"x", "y"}, rest)
+	require.Len(t, nodes, 3)
+	require.Equal(t, ipld.Kind_Map, nodes[0].Kind())
+	require.Equal(t, ipld.Kind_List, nodes[1].Kind())
+	require.Equal(t, ipld.Kind_Link, nodes[2].Kind())
+
+	// resolves within the block
+	lnk, rest, nodes, err = resolver.ResolveHop(ctx, blk.Cid(), nd, []string{"c"})
+	require.NoError(t, err)
+	require.Nil(t, lnk)
+	require.Empty(t, rest)
+	require.Len(t, nodes, 1)
+	s, err := nodes[0].AsString()
+	require.NoError(t, err)
+	require.Equal(t, "d", s)
+
+	_, _, nodes, err = resolver.ResolveHop(ctx, blk.Cid(), nd, []string{"a", "missing"})
+	require.Equal(t, resolver.ErrNoLink{Name: "missing", Node: blk.Cid()}, err)
+	require.Len(t, nodes, 1)
+
+	_, _, _, err = resolver.ResolveHop(ctx, blk.Cid(), nd, []string{"c", "d"})
+	require.ErrorAs(t, err, &resolver.ErrNotTraversable{})
+}
+
+func TestResolvePath_UnmatchedSegment(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("child", b))
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b))
+	p := path.FromString(path.FromCid(a.Cid()).String() + "/child/missing/more")
+
+	// the nodes matched so far, without an error
+	nodes, err := r.ResolvePathComponents(ctx, p)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+
+	_, _, err = r.ResolvePath(ctx, p)
+	require.EqualError(t, err, fmt.Sprintf("path %v did not resolve to a node: %v", p, resolver.ErrNoLink{Name: "missing", Node: b.Cid()}))
+	require.ErrorAs(t, err, &resolver.ErrNoLink{})
+
+	nodes, err = resolver.ResolveLinks(ctx, r, nodes[0], []string{"child", "missing"})
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+}
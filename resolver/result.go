@@ -0,0 +1,175 @@
+package resolver
+
+import (
+	cid "github.com/ipfs/go-cid"
+	path "github.com/ipfs/go-path"
+	"github.com/ipld/go-ipld-prime"
+)
+
+// ResolvedSegment describes the node reached by resolving one segment of a
+// path.
+type ResolvedSegment struct {
+	// Name is the path segment. It is empty for the root of a path.
+	Name string
+	// Segment is the path segment as it was looked up: an integer for list
+	// indexes and a string for map keys.
+	Segment ipld.PathSegment
+	// Node is the node the segment resolved to.
+	Node ipld.Node
+	// Block is the cid of the block containing Node.
+	Block cid.Cid
+	// Depth is the depth of Node within Block; the root node of a block is
+	// at depth 0.
+	Depth int
+	// Boundary is true when a block boundary was crossed to reach Node,
+	// that is when Node is the root of a block loaded for this segment.
+	Boundary bool
+	// Inline is true when Node is the root of a block inlined in an
+	// identity cid, which was decoded rather than fetched.
+	Inline bool
+}
+
+// ResolveResult describes every step of the resolution of a path.
+type ResolveResult struct {
+	// Root is the root node of the path.
+	Root ResolvedSegment
+	// Segments holds the resolution of each segment of the path after the
+	// root, in order.
+	Segments []ResolvedSegment
+	// Remainder is the list of path segments to traverse from the last block
+	// boundary to the final node within its block.
+	Remainder []string
+	// Names holds the names resolved, in order, to find the root of an
+	// /ipns/ path.
+	Names []NameHop
+}
+
+// Last returns the resolution of the final segment of the path, or the
+// root if the path has no segments.
+func (r *ResolveResult) Last() ResolvedSegment {
+	if len(r.Segments) == 0 {
+		return r.Root
+	}
+	return r.Segments[len(r.Segments)-1]
+}
+
+// Path returns the ipld-prime path from the root to the final node, with
+// segments typed as they were looked up.
+func (r *ResolveResult) Path() ipld.Path {
+	segments := make([]ipld.PathSegment, len(r.Segments))
+	for i, s := range r.Segments {
+		segments[i] = s.Segment
+	}
+	return ipld.NewPath(segments)
+}
+
+// Nodes returns the nodes forming the path, starting with the root.
+func (r *ResolveResult) Nodes() []ipld.Node {
+	nodes := make([]ipld.Node, 0, len(r.Segments)+1)
+	nodes = append(nodes, r.Root.Node)
+	for _, s := range r.Segments {
+		nodes = append(nodes, s.Node)
+	}
+	return nodes
+}
+
+// Blocks returns the cids of the blocks traversed to resolve the path, in
+// traversal order and starting with the root block.
+func (r *ResolveResult) Blocks() []cid.Cid {
+	blocks := []cid.Cid{r.Root.Block}
+	for _, s := range r.Segments {
+		if s.Boundary {
+			blocks = append(blocks, s.Block)
+		}
+	}
+	return blocks
+}
+
+// remainder returns the names of the segments resolved within the block of
+// the last segment.
+func remainder(segments []ResolvedSegment) []string {
+	rest := []string{}
+	for i := len(segments) - 1; i >= 0 && !segments[i].Boundary; i-- {
+		rest = append([]string{segments[i].Name}, rest...)
+	}
+	return rest
+}
+
+// ErrPartialResolution is returned by Resolve and ResolveToLastNode when a
+// path fails to resolve after its root was loaded. It carries what was resolved before the failing
+// segment, so that the resolution can be reported or resumed from the last
+// block reached. Its message is the one of Err.
+type ErrPartialResolution struct {
+	// Prefix is the deepest prefix of the path that resolved, as an
+	// immutable path.
+	Prefix path.Path
+	// Cid is the cid of the block containing the node Prefix resolved to.
+	Cid cid.Cid
+	// Remainder is the list of path segments to traverse from Cid to the
+	// node Prefix resolved to.
+	Remainder []string
+	// Result holds the resolution of Prefix: its nodes and blocks. When
+	// ResolveToLastNode resumed the resolution from an indexed or cached
+	// prefix, Result starts from the block of that prefix.
+	Result *ResolveResult
+	// Index is the index of the failing segment among the segments
+	// following the root of the path.
+	Index int
+	// Err is the error met resolving the failing segment.
+	Err error
+}
+
+// Error implements the Error interface for ErrPartialResolution.
+func (e ErrPartialResolution) Error() string {
+	return e.Err.Error()
+}
+
+func (e ErrPartialResolution) Unwrap() error {
+	return e.Err
+}
+
+// partialResolution wraps err, met resolving the segment following those
+// resolved in res, in an ErrPartialResolution.
+func partialResolution(ns string, res *ResolveResult, err error) error {
+	if ns == "" {
+		ns = nsIPFS
+	}
+	segments := []string{res.Root.Block.String()}
+	for _, s := range res.Segments {
+		segments = append(segments, s.Name)
+	}
+	prefix, perr := path.FromSegments("/"+ns+"/", segments...)
+	if perr != nil {
+		return err
+	}
+	last := res.Last()
+	return ErrPartialResolution{
+		Prefix:    prefix,
+		Cid:       last.Block,
+		Remainder: append([]string{}, res.Remainder...),
+		Result:    res,
+		Index:     len(res.Segments),
+		Err:       err,
+	}
+}
+
+// resumedPartialResolution is partialResolution for the resolution res of
+// the segments of p after its first skipped ones, resumed from the block the
+// skipped segments lead to. The prefix and index reported are those of the
+// path made of root and p.
+func resumedPartialResolution(ns string, root cid.Cid, p []string, skipped int, res *ResolveResult, err error) error {
+	if res == nil {
+		return err
+	}
+	perr, ok := partialResolution(ns, res, err).(ErrPartialResolution)
+	if !ok {
+		return err
+	}
+	perr.Index += skipped
+	prefix, pathErr := path.FromSegments("/"+ns+"/", append([]string{root.String()}, p[:perr.Index]...)...)
+	if pathErr != nil {
+		return err
+	}
+	perr.Prefix = prefix
+	return perr
+}
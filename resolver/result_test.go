@@ -0,0 +1,153 @@
+package resolver_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	bsfetcher "github.com/ipfs/go-fetcher/impl/blockservice"
+	dagmock "github.com/ipfs/go-merkledag/test"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveResult(t *testing.T) {
+	ctx := context.Background()
+	bsrv := dagmock.Bserv()
+
+	a := randNode()
+	leaf := cborBlock(t, `{"x":{"y":"z"}}`)
+	root := cborBlock(t, `{"foo":{"bar":{"/":"`+leaf.Cid().String()+`"},"baz":{"/":"`+a.Cid().String()+`"}}}`)
+	require.NoError(t, bsrv.AddBlock(ctx, a))
+	require.NoError(t, bsrv.AddBlock(ctx, leaf))
+	require.NoError(t, bsrv.AddBlock(ctx, root))
+	r := resolver.NewBasicResolver(bsfetcher.NewFetcherConfig(bsrv))
+
+	res, err := resolver.Resolve(ctx, r, path.FromString(root.Cid().String()+"/foo/bar/x/y"))
+	require.NoError(t, err)
+
+	assert.Equal(t, root.Cid(), res.Root.Block)
+	assert.True(t, res.Root.Boundary)
+	expected := []struct {
+		name     string
+		block    cid.Cid
+		depth    int
+		boundary bool
+	}{
+		{"foo", root.Cid(), 1, false},
+		{"bar", leaf.Cid(), 0, true},
+		{"x", leaf.Cid(), 1, false},
+		{"y", leaf.Cid(), 2, false},
+	}
+	require.Len(t, res.Segments, len(expected))
+	for i, e := range expected {
+		s := res.Segments[i]
+		assert.Equal(t, e.name, s.Name)
+		assert.Equal(t, e.block, s.Block, "segment %s", e.name)
+		assert.Equal(t, e.depth, s.Depth, "segment %s", e.name)
+		assert.Equal(t, e.boundary, s.Boundary, "segment %s", e.name)
+	}
+	str, err := res.Last().Node.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "z", str)
+	assert.Equal(t, []string{"x", "y"}, res.Remainder)
+	assert.Equal(t, []cid.Cid{root.Cid(), leaf.Cid()}, res.Blocks())
+	assert.Len(t, res.Nodes(), 5)
+
+	// a path ending on a link loads the linked block
+	res, err = resolver.Resolve(ctx, r, path.FromString(root.Cid().String()+"/foo/baz"))
+	require.NoError(t, err)
+	assert.Equal(t, a.Cid(), res.Last().Block)
+	assert.True(t, res.Last().Boundary)
+	assert.Empty(t, res.Remainder)
+
+	// the root alone
+	res, err = resolver.Resolve(ctx, r, path.FromCid(root.Cid()))
+	require.NoError(t, err)
+	assert.Empty(t, res.Segments)
+	assert.Equal(t, root.Cid(), res.Last().Block)
+}
+
+func TestErrPartialResolution(t *testing.T) {
+	ctx := context.Background()
+	bsrv := dagmock.Bserv()
+
+	missing := randNode()
+	leaf := cborBlock(t, `{"x":{"gone":{"/":"`+missing.Cid().String()+`"}}}`)
+	root := cborBlock(t, `{"foo":{"bar":{"/":"`+leaf.Cid().String()+`"}}}`)
+	require.NoError(t, bsrv.AddBlock(ctx, leaf))
+	require.NoError(t, bsrv.AddBlock(ctx, root))
+	r := resolver.NewBasicResolver(bsfetcher.NewFetcherConfig(bsrv))
+
+	_, err := resolver.Resolve(ctx, r, path.FromString("/ipld/"+root.Cid().String()+"/foo/bar/x/nope/more"))
+	require.ErrorAs(t, err, &resolver.ErrNoLink{})
+	var perr resolver.ErrPartialResolution
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, path.FromString("/ipld/"+root.Cid().String()+"/foo/bar/x"), perr.Prefix)
+	assert.Equal(t, leaf.Cid(), perr.Cid)
+	assert.Equal(t, []string{"x"}, perr.Remainder)
+	assert.Equal(t, 3, perr.Index)
+	assert.Len(t, perr.Result.Nodes(), 4)
+	assert.Equal(t, resolver.ErrNoLink{Name: "nope", Node: leaf.Cid()}, perr.Err)
+
+	// a missing block
+	tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = resolver.Resolve(tctx, r, path.FromString("/ipld/"+root.Cid().String()+"/foo/bar/x/gone/y"))
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, path.FromString("/ipld/"+root.Cid().String()+"/foo/bar/x"), perr.Prefix)
+	assert.Equal(t, 3, perr.Index)
+
+	// and so does ResolveToLastNode
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString("/ipld/"+root.Cid().String()+"/foo/bar/x/nope"))
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, path.FromString("/ipld/"+root.Cid().String()+"/foo/bar/x"), perr.Prefix)
+	assert.Equal(t, 3, perr.Index)
+	assert.Equal(t, resolver.ErrNoLink{Name: "nope", Node: leaf.Cid()}, perr.Err)
+
+	// the other methods return the error alone
+	_, err = r.ResolvePathComponents(ctx, path.FromString("/ipld/"+root.Cid().String()+"/foo/bar/x/nope"))
+	require.NoError(t, err)
+
+	// a failing root is not a partial resolution
+	_, err = resolver.Resolve(tctx, r, path.FromCid(missing.Cid()))
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &perr))
+}
+
+// resolveWrapper is a third-party Resolver wrapper taking part in Resolve.
+type resolveWrapper struct {
+	resolver.Resolver
+	calls int
+}
+
+func (w *resolveWrapper) Resolve(ctx context.Context, fpath path.Path) (*resolver.ResolveResult, error) {
+	w.calls++
+	return resolver.Resolve(ctx, w.Resolver, fpath)
+}
+
+func TestResolveWrappers(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("b", b))
+	p := path.FromString("/ipfs/" + a.Cid().String() + "/b")
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b))
+
+	cr, err := resolver.NewCachingResolver(r, 16)
+	require.NoError(t, err)
+	w := &resolveWrapper{Resolver: cr}
+	res, err := resolver.Resolve(ctx, w, p)
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), res.Last().Block)
+	require.Equal(t, 1, w.calls)
+
+	// resolvers without the method are reported
+	_, err = resolver.Resolve(ctx, &recordingResolver{Resolver: r}, p)
+	require.Equal(t, resolver.ErrUnsupported{Resolver: &recordingResolver{Resolver: r}, Method: "Resolve"}, err)
+}
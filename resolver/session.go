@@ -0,0 +1,61 @@
+package resolver
+
+import (
+	"context"
+
+	"github.com/ipfs/go-fetcher"
+)
+
+// SessionResolver is a Resolver whose resolutions can fetch blocks through
+// a single session instead of a session each, so that several related
+// resolutions share one session. The resolvers returned by
+// NewBasicResolver and NewCachingResolver are SessionResolvers.
+type SessionResolver interface {
+	Resolver
+	// NewSession returns a resolver making every resolution fetch blocks
+	// through one new session of the fetcher factory of the resolver,
+	// started with ctx. The session ends when ctx is done.
+	NewSession(ctx context.Context) (Resolver, error)
+	// WithSession returns a resolver making every resolution fetch blocks
+	// through session, a fetcher session the caller holds.
+	WithSession(session fetcher.Fetcher) (Resolver, error)
+}
+
+var _ SessionResolver = (*basicResolver)(nil)
+
+// NewSessionResolver returns a resolver making every resolution fetch blocks
+// through one session of the fetcher factory of r, started with ctx (see
+// SessionResolver). r must be a SessionResolver, as the resolvers of this
+// package are.
+func NewSessionResolver(ctx context.Context, r Resolver) (Resolver, error) {
+	sr, ok := r.(SessionResolver)
+	if !ok {
+		return nil, ErrUnsupported{Resolver: r, Method: "NewSession"}
+	}
+	return sr.NewSession(ctx)
+}
+
+// NewSession returns a resolver fetching blocks through one new session of
+// its fetcher factory (see SessionResolver).
+//
+// With a fetcher factory able to change its NodeReifier, such as a
+// FetcherFactory, nodes are still reified for the namespace of each path;
+// other factories apply their own NodeReifier to every path. Fetches the
+// block timeout gives up on are left to the session.
+func (r *basicResolver) NewSession(ctx context.Context) (Resolver, error) {
+	sr := *r
+	sr.session = newFetchSession(ctx, r.FetcherFactory)
+	return &sr, nil
+}
+
+// WithSession returns a resolver fetching blocks through session (see
+// SessionResolver).
+//
+// Nodes are reified by session for every path, whatever its namespace, and
+// resolutions with a byte budget fail. Fetches the block timeout gives up on
+// are left to session.
+func (r *basicResolver) WithSession(session fetcher.Fetcher) (Resolver, error) {
+	sr := *r
+	sr.session = newFetcherSession(session)
+	return &sr, nil
+}
@@ -0,0 +1,105 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSessionResolver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	require.NoError(t, b.AddNodeLink("c", c))
+	require.NoError(t, a.AddNodeLink("b", b))
+
+	factory := &countingFactory{Factory: unixfsFetcherFactory(t, a, b, c)}
+	r := resolver.NewBasicResolver(factory)
+	shared, err := resolver.NewSessionResolver(ctx, r)
+	require.NoError(t, err)
+
+	last, _, err := shared.ResolveToLastNode(ctx, path.FromString("/ipfs/"+a.Cid().String()+"/b/c"))
+	require.NoError(t, err)
+	require.Equal(t, c.Cid(), last)
+
+	root, _, err := shared.ResolvePath(ctx, path.FromCid(a.Cid()))
+	require.NoError(t, err)
+	nodes, err := resolver.ResolveLinks(ctx, shared, root, []string{"b", "c"})
+	require.NoError(t, err)
+	require.Len(t, nodes, 3)
+
+	// only the shared session was used
+	require.Equal(t, 1, factory.sessions)
+
+	_, err = resolver.ResolveLinks(ctx, r, root, []string{"b"})
+	require.NoError(t, err)
+	require.Equal(t, 2, factory.sessions)
+}
+
+func TestNewSessionResolverNamespaces(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("foo", b))
+	shared, err := resolver.NewSessionResolver(ctx, resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b)))
+	require.NoError(t, err)
+
+	// each path is resolved with the pathing of its namespace
+	c, _, err := shared.ResolveToLastNode(ctx, path.FromString("/ipfs/"+a.Cid().String()+"/foo"))
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), c)
+
+	c, rest, err := shared.ResolveToLastNode(ctx, path.FromString("/ipld/"+a.Cid().String()+"/Links/0/Hash"))
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), c)
+	require.Empty(t, rest)
+
+	_, _, err = shared.ResolveToLastNode(ctx, path.FromString("/ipfs/"+a.Cid().String()+"/Links/0/Hash"))
+	require.ErrorAs(t, err, &resolver.ErrNoLink{})
+}
+
+func TestSessionResolverWithSession(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("b", b))
+	p := path.FromString("/ipfs/" + a.Cid().String() + "/b")
+
+	factory := &countingFactory{Factory: unixfsFetcherFactory(t, a, b)}
+	cr, err := resolver.NewCachingResolver(resolver.NewBasicResolver(factory), 16)
+	require.NoError(t, err)
+
+	// every resolution goes through the session of the caller
+	session := factory.NewSession(ctx)
+	shared, err := cr.WithSession(session)
+	require.NoError(t, err)
+	last, _, err := shared.ResolveToLastNode(ctx, p)
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), last)
+	root, _, err := shared.ResolvePath(ctx, path.FromCid(a.Cid()))
+	require.NoError(t, err)
+	nodes, err := resolver.ResolveLinks(ctx, shared, root, []string{"b"})
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	require.Equal(t, 1, factory.sessions)
+
+	// and the cache is shared with the resolver it was derived from
+	last, _, err = cr.ResolveToLastNode(ctx, p)
+	require.NoError(t, err)
+	require.Equal(t, b.Cid(), last)
+	require.Equal(t, resolver.CacheStats{Hits: 1, Misses: 1}, cr.Stats())
+	require.Equal(t, 1, factory.sessions)
+
+	_, err = resolver.NewSessionResolver(ctx, &recordingResolver{Resolver: cr})
+	require.ErrorAs(t, err, &resolver.ErrUnsupported{})
+}
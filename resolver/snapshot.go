@@ -0,0 +1,139 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	cid "github.com/ipfs/go-cid"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/internal"
+)
+
+// Snapshot holds the resolution of several sub-paths of a single root,
+// resolved against the same immutable root cid.
+type Snapshot struct {
+	// Root is the immutable /ipfs/ or /ipld/ path the sub-paths were
+	// resolved under.
+	Root path.Path
+	// Names holds the names resolved, in order, to find the root.
+	Names []NameHop
+	// Entries holds the resolution of each sub-path, in the order they were
+	// given.
+	Entries []SnapshotEntry
+}
+
+// SnapshotEntry is the resolution of one sub-path of a Snapshot, as returned
+// by ResolveToLastNode.
+type SnapshotEntry struct {
+	// SubPath is the sub-path, as given.
+	SubPath string
+	// Cid is the cid of the last block referenced by the sub-path.
+	Cid cid.Cid
+	// Remainder is the list of path segments to traverse from the last block
+	// boundary to the final node within its block.
+	Remainder []string
+	// Err is the error met resolving the sub-path, if any.
+	Err error
+}
+
+// ErrPartialSnapshot is returned along with a Snapshot when some of its
+// sub-paths failed to resolve. The error of each is in its SnapshotEntry.
+type ErrPartialSnapshot struct {
+	Root path.Path
+	// Failed lists the sub-paths that failed to resolve.
+	Failed []string
+	Total  int
+}
+
+// Error implements the Error interface for ErrPartialSnapshot with a useful
+// human readable message.
+func (e ErrPartialSnapshot) Error() string {
+	return fmt.Sprintf("%d of %d sub-paths of %s failed to resolve: %s", len(e.Failed), e.Total, e.Root, strings.Join(e.Failed, ", "))
+}
+
+// ResolveSnapshot resolves the root of fpath once with r, resolving the name
+// at the root of an /ipns/ path, and then resolves each of the sub-paths
+// under it with a single fetcher session. Sub-paths are relative to fpath,
+// with segments separated by slashes.
+//
+// If some sub-paths fail to resolve, the snapshot is returned along with an
+// ErrPartialSnapshot. Each sub-path is bounded by the resolver timeouts on
+// its own, while the budget of the resolver applies to the whole snapshot.
+//
+// r must have a method ResolveSnapshot(ctx, fpath, subPaths), as the
+// resolvers of this package do.
+func ResolveSnapshot(ctx context.Context, r Resolver, fpath path.Path, subPaths []string) (*Snapshot, error) {
+	sr, ok := r.(interface {
+		ResolveSnapshot(context.Context, path.Path, []string) (*Snapshot, error)
+	})
+	if !ok {
+		return nil, ErrUnsupported{Resolver: r, Method: "ResolveSnapshot"}
+	}
+	return sr.ResolveSnapshot(ctx, fpath, subPaths)
+}
+
+// ResolveSnapshot resolves the sub-paths of fpath against a single root
+// (see the ResolveSnapshot function).
+func (r *basicResolver) ResolveSnapshot(ctx context.Context, fpath path.Path, subPaths []string) (*Snapshot, error) {
+	ctx, span := internal.StartSpan(ctx, "basicResolver.ResolveSnapshot", trace.WithAttributes(attribute.Stringer("Path", fpath), attribute.Int("SubPaths", len(subPaths))))
+	defer span.End()
+
+	// validate path
+	if err := fpath.IsValid(); err != nil {
+		return nil, err
+	}
+
+	// the names at the root are resolved within the scope of the root
+	// walker, each sub-path within its own
+	sctx, w := r.newWalker(ctx, "")
+	defer w.close()
+	c, p, hops, err := r.resolveRoot(sctx, w, fpath)
+	if err != nil {
+		return nil, err
+	}
+	root, err := path.FromSegments("/"+w.ns+"/", append([]string{c.String()}, p...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{Root: root, Names: hops, Entries: make([]SnapshotEntry, len(subPaths))}
+	var failed []string
+	for i, sub := range subPaths {
+		e := &snap.Entries[i]
+		e.SubPath = sub
+		sctx, sw := r.subWalker(ctx, w)
+		e.Cid, e.Remainder, e.Err = r.resolveSubPath(sctx, sw, root, c, p, sub)
+		sw.close()
+		if e.Err != nil {
+			failed = append(failed, sub)
+		}
+	}
+	if len(failed) > 0 {
+		return snap, ErrPartialSnapshot{Root: root, Failed: failed, Total: len(subPaths)}
+	}
+	return snap, nil
+}
+
+// resolveSubPath resolves sub under the path made of c and the segments p,
+// as ResolveToLastNode does.
+func (r *basicResolver) resolveSubPath(ctx context.Context, w *walker, root path.Path, c cid.Cid, p []string, sub string) (cid.Cid, []string, error) {
+	segments := append([]string{}, p...)
+	if sub = strings.Trim(sub, "/"); sub != "" {
+		segments = append(segments, strings.Split(sub, "/")...)
+	}
+	if len(segments) == 0 {
+		return c, nil, nil
+	}
+	fpath := path.FromString(root.String() + "/" + sub)
+
+	// resolve all segments, without loading a link found under the last one
+	res, err := r.resolve(ctx, w, c, segments, false)
+	if err != nil {
+		return cid.Cid{}, nil, err
+	}
+	return lastNode(fpath, res.Last(), res.Remainder)
+}
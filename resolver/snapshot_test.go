@@ -0,0 +1,129 @@
+package resolver_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-fetcher"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/stretchr/testify/require"
+)
+
+// countingFactory counts the sessions created.
+type countingFactory struct {
+	fetcher.Factory
+	sessions int
+}
+
+func (f *countingFactory) NewSession(ctx context.Context) fetcher.Fetcher {
+	f.sessions++
+	return f.Factory.NewSession(ctx)
+}
+
+// countingNameResolver counts the names resolved.
+type countingNameResolver struct {
+	resolver.NameResolver
+	lookups int
+}
+
+func (nr *countingNameResolver) ResolveName(ctx context.Context, name string) (path.Path, time.Duration, error) {
+	nr.lookups++
+	return nr.NameResolver.ResolveName(ctx, name)
+}
+
+func TestResolveSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	require.NoError(t, b.AddNodeLink("c", c))
+	require.NoError(t, a.AddNodeLink("b", b))
+
+	names := resolver.NewMemoryNameResolver()
+	names.Publish("site", path.FromCid(a.Cid()), 0)
+	nr := &countingNameResolver{NameResolver: names}
+	factory := &countingFactory{Factory: unixfsFetcherFactory(t, a, b, c)}
+	r := resolver.NewBasicResolver(factory, resolver.WithNameResolver(nr))
+
+	snap, err := resolver.ResolveSnapshot(ctx, r, path.FromString("/ipns/site"), []string{"", "b", "/b/c", "missing"})
+	require.Equal(t, resolver.ErrPartialSnapshot{Root: path.FromCid(a.Cid()), Failed: []string{"missing"}, Total: 4}, err)
+	require.Equal(t, path.FromCid(a.Cid()), snap.Root)
+	require.Len(t, snap.Names, 1)
+	require.Equal(t, 1, nr.lookups)
+	require.Equal(t, 1, factory.sessions)
+
+	require.Len(t, snap.Entries, 4)
+	require.Equal(t, a.Cid(), snap.Entries[0].Cid)
+	require.Equal(t, b.Cid(), snap.Entries[1].Cid)
+	require.Equal(t, "/b/c", snap.Entries[2].SubPath)
+	require.Equal(t, c.Cid(), snap.Entries[2].Cid)
+	require.Empty(t, snap.Entries[2].Remainder)
+	require.ErrorAs(t, snap.Entries[3].Err, &resolver.ErrNoLink{})
+
+	// sub-paths are relative to the given path
+	snap, err = resolver.ResolveSnapshot(ctx, r, path.FromString("/ipns/site/b"), []string{"c"})
+	require.NoError(t, err)
+	require.Equal(t, path.FromString(path.FromCid(a.Cid()).String()+"/b"), snap.Root)
+	require.Equal(t, c.Cid(), snap.Entries[0].Cid)
+
+	// caching resolvers pass it to the resolver they wrap
+	cr, err := resolver.NewCachingResolver(r, 16)
+	require.NoError(t, err)
+	snap, err = resolver.ResolveSnapshot(ctx, cr, path.FromCid(a.Cid()), []string{"b/c"})
+	require.NoError(t, err)
+	require.Equal(t, c.Cid(), snap.Entries[0].Cid)
+}
+
+// stallingFactory never delivers the block stalled, and fails every fetch
+// once the session has ended.
+type stallingFactory struct {
+	fetcher.Factory
+	stalled cid.Cid
+}
+
+func (f stallingFactory) NewSession(ctx context.Context) fetcher.Fetcher {
+	return stallingFetcher{Fetcher: f.Factory.NewSession(ctx), ctx: ctx, stalled: f.stalled}
+}
+
+type stallingFetcher struct {
+	fetcher.Fetcher
+	ctx     context.Context
+	stalled cid.Cid
+}
+
+func (f stallingFetcher) BlockOfType(ctx context.Context, lnk ipld.Link, np ipld.NodePrototype) (ipld.Node, error) {
+	if lnk.(cidlink.Link).Cid.Equals(f.stalled) {
+		<-f.ctx.Done()
+	}
+	if err := f.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.BlockOfType(ctx, lnk, np)
+}
+
+func TestResolveSnapshotTimeoutPerSubPath(t *testing.T) {
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	slow := randNode()
+	require.NoError(t, b.AddNodeLink("c", c))
+	require.NoError(t, a.AddNodeLink("b", b))
+	require.NoError(t, a.AddNodeLink("slow", slow))
+
+	factory := stallingFactory{Factory: unixfsFetcherFactory(t, a, b, c, slow), stalled: slow.Cid()}
+	r := resolver.NewBasicResolver(factory, resolver.WithBlockTimeout(20*time.Millisecond))
+
+	// a sub-path timing out leaves the session to the next ones
+	snap, err := resolver.ResolveSnapshot(context.Background(), r, path.FromCid(a.Cid()), []string{"slow/x", "b/c"})
+	require.Equal(t, resolver.ErrPartialSnapshot{Root: path.FromCid(a.Cid()), Failed: []string{"slow/x"}, Total: 2}, err)
+	require.True(t, errors.Is(snap.Entries[0].Err, context.DeadlineExceeded), "expected a deadline error, got %v", snap.Entries[0].Err)
+	require.NoError(t, snap.Entries[1].Err)
+	require.Equal(t, c.Cid(), snap.Entries[1].Cid)
+}
@@ -0,0 +1,62 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/internal"
+)
+
+// ErrStopResolution can be returned by the function given to ResolveEach to
+// stop the resolution early. ResolveEach then returns nil.
+var ErrStopResolution = errors.New("stop resolution")
+
+// ResolveEach walks fpath with r and calls fn with the root of the path and
+// then with each of its segments, in order, as soon as they are resolved.
+// The root is reported with an empty Name.
+//
+// The resolution stops at the first error returned by fn, which ResolveEach
+// returns unless it is ErrStopResolution, or when ctx is done. Nodes given to
+// fn are only guaranteed to be fully loadable until fn returns.
+//
+// r must have a method ResolveEach(ctx, fpath, fn), as the resolvers of this
+// package do.
+func ResolveEach(ctx context.Context, r Resolver, fpath path.Path, fn func(ResolvedSegment) error) error {
+	er, ok := r.(interface {
+		ResolveEach(context.Context, path.Path, func(ResolvedSegment) error) error
+	})
+	if !ok {
+		return ErrUnsupported{Resolver: r, Method: "ResolveEach"}
+	}
+	return er.ResolveEach(ctx, fpath, fn)
+}
+
+// ResolveEach walks fpath and calls fn with the resolution of its root and
+// of each of its segments (see the ResolveEach function).
+func (r *basicResolver) ResolveEach(ctx context.Context, fpath path.Path, fn func(ResolvedSegment) error) error {
+	ctx, span := internal.StartSpan(ctx, "basicResolver.ResolveEach", trace.WithAttributes(attribute.Stringer("Path", fpath)))
+	defer span.End()
+
+	// validate path
+	if err := fpath.IsValid(); err != nil {
+		return err
+	}
+
+	ctx, w := r.newWalker(ctx, "")
+	defer w.close()
+	c, p, _, err := r.resolveRoot(ctx, w, fpath)
+	if err != nil {
+		return err
+	}
+	w.onStep = fn
+
+	_, err = r.resolve(ctx, w, c, p, true)
+	if errors.Is(err, ErrStopResolution) {
+		return nil
+	}
+	return err
+}
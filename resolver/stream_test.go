@@ -0,0 +1,77 @@
+package resolver_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveEach(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	require.NoError(t, b.AddNodeLink("c", c))
+	require.NoError(t, a.AddNodeLink("b", b))
+	p := path.FromString("/ipfs/" + a.Cid().String() + "/b/c")
+
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b, c))
+	var steps []resolver.ResolvedSegment
+	require.NoError(t, resolver.ResolveEach(ctx, r, p, func(seg resolver.ResolvedSegment) error {
+		steps = append(steps, seg)
+		return nil
+	}))
+	require.Len(t, steps, 3)
+	require.Equal(t, "", steps[0].Name)
+	require.Equal(t, a.Cid(), steps[0].Block)
+	require.Equal(t, "b", steps[1].Name)
+	require.Equal(t, b.Cid(), steps[1].Block)
+	require.Equal(t, "c", steps[2].Name)
+	require.Equal(t, c.Cid(), steps[2].Block)
+
+	// stopping early does not fetch the rest of the path, c is missing here
+	r = resolver.NewBasicResolver(unixfsFetcherFactory(t, a, b))
+	var names []string
+	require.NoError(t, resolver.ResolveEach(ctx, r, p, func(seg resolver.ResolvedSegment) error {
+		names = append(names, seg.Name)
+		if seg.Name == "b" {
+			return resolver.ErrStopResolution
+		}
+		return nil
+	}))
+	require.Equal(t, []string{"", "b"}, names)
+
+	errFail := errors.New("fail")
+	err := resolver.ResolveEach(ctx, r, p, func(seg resolver.ResolvedSegment) error {
+		return errFail
+	})
+	require.ErrorIs(t, err, errFail)
+
+	// caching resolvers pass it to the resolver they wrap
+	cr, err := resolver.NewCachingResolver(r, 16)
+	require.NoError(t, err)
+	names = nil
+	require.NoError(t, resolver.ResolveEach(ctx, cr, path.FromCid(a.Cid()), func(seg resolver.ResolvedSegment) error {
+		names = append(names, seg.Name)
+		return nil
+	}))
+	require.Equal(t, []string{""}, names)
+
+	// cancellation is honored between steps
+	cctx, ccancel := context.WithCancel(ctx)
+	names = nil
+	err = resolver.ResolveEach(cctx, r, p, func(seg resolver.ResolvedSegment) error {
+		names = append(names, seg.Name)
+		ccancel()
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, []string{""}, names)
+}
@@ -0,0 +1,181 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/internal"
+	"github.com/ipfs/go-path/internal/car"
+)
+
+// ProofFault describes what is wrong with an invalid proof.
+type ProofFault string
+
+const (
+	// ProofWrongRoot means the root of the path is not a root of the proof.
+	ProofWrongRoot ProofFault = "wrong root"
+	// ProofMissingBlock means a block needed to resolve the path is not in
+	// the proof.
+	ProofMissingBlock ProofFault = "missing block"
+	// ProofHashMismatch means the data of a block does not match its cid.
+	ProofHashMismatch ProofFault = "hash mismatch"
+	// ProofExtraneousBlock means the proof holds a block that is not needed
+	// to resolve the path, or holds a block more than once.
+	ProofExtraneousBlock ProofFault = "extraneous block"
+	// ProofWrongTarget means the path does not resolve to the claimed cid.
+	ProofWrongTarget ProofFault = "wrong target"
+)
+
+// ErrInvalidProof is returned when a proof does not show that a path
+// resolves to the claimed cid.
+type ErrInvalidProof struct {
+	Fault ProofFault
+	// Cid is the block at fault. For ProofWrongRoot it is the root of the
+	// path, and for ProofWrongTarget the cid the path actually resolves to.
+	Cid cid.Cid
+}
+
+// Error implements the Error interface for ErrInvalidProof with a useful
+// human readable message.
+func (e ErrInvalidProof) Error() string {
+	switch e.Fault {
+	case ProofWrongRoot:
+		return fmt.Sprintf("invalid proof: %s is not a root of the car", e.Cid)
+	case ProofWrongTarget:
+		return fmt.Sprintf("invalid proof: path resolves to %s", e.Cid)
+	default:
+		return fmt.Sprintf("invalid proof: %s %s", e.Fault, e.Cid)
+	}
+}
+
+// VerifyCAR checks, using only the blocks of the CARv1 stream r, that fpath
+// resolves to target. The stream must hold exactly the blocks WriteCAR
+// writes for fpath and scope: every block is checked against its cid, and a
+// block that is missing or not needed fails the verification with an
+// ErrInvalidProof.
+//
+// The path resolves to the cid of the block holding its terminal element, as
+// with WriteCAR the last link of the path is followed.
+func VerifyCAR(ctx context.Context, r io.Reader, fpath path.Path, scope DagScope, target cid.Cid, opts ...Option) error {
+	ctx, span := internal.StartSpan(ctx, "VerifyCAR", trace.WithAttributes(attribute.Stringer("Path", fpath), attribute.String("Scope", string(scope)), attribute.Stringer("Target", target)))
+	defer span.End()
+
+	switch scope {
+	case DagScopeBlock, DagScopeEntity, DagScopeAll:
+	default:
+		return fmt.Errorf("unknown dag scope %q", scope)
+	}
+
+	// validate path
+	if err := fpath.IsValid(); err != nil {
+		return err
+	}
+
+	c, p, err := path.SplitAbsPath(fpath)
+	if err != nil {
+		return err
+	}
+
+	proof, err := readProof(r, c)
+	if err != nil {
+		return err
+	}
+
+	res, err := resolveProof(ctx, proof, nil, pathNamespace(fpath, nil), c, p, scope, opts)
+	if err != nil {
+		// report the block missing from the proof even when the error was
+		// swallowed while loading it
+		if proof.missing.Defined() {
+			return ErrInvalidProof{Fault: ProofMissingBlock, Cid: proof.missing}
+		}
+		return err
+	}
+
+	for _, k := range proof.order {
+		if !proof.used.Has(k) {
+			return ErrInvalidProof{Fault: ProofExtraneousBlock, Cid: k}
+		}
+	}
+
+	if last := res.Last().Block; !last.Equals(target) {
+		return ErrInvalidProof{Fault: ProofWrongTarget, Cid: last}
+	}
+	return nil
+}
+
+// proofBlocks is a BlockGetter serving the blocks of a proof and recording
+// which of them are used.
+type proofBlocks struct {
+	blocks map[cid.Cid]blocks.Block
+	// order lists the cids of the blocks in the order of the proof.
+	order []cid.Cid
+	used  *cid.Set
+	// missing is the first block requested that is not in the proof.
+	missing cid.Cid
+}
+
+// readProof reads the blocks of the CARv1 stream r, which must list root as
+// one of its roots, and checks each of them against its cid.
+func readProof(r io.Reader, root cid.Cid) (*proofBlocks, error) {
+	cr, err := car.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if !hasCid(cr.Roots, root) {
+		return nil, ErrInvalidProof{Fault: ProofWrongRoot, Cid: root}
+	}
+
+	proof := &proofBlocks{blocks: make(map[cid.Cid]blocks.Block), used: cid.NewSet()}
+	for {
+		blk, err := cr.Next()
+		if errors.Is(err, io.EOF) {
+			return proof, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		k := blk.Cid()
+		actual, err := k.Prefix().Sum(blk.RawData())
+		if err != nil {
+			return nil, fmt.Errorf("could not hash block %s: %w", k, err)
+		}
+		if !actual.Equals(k) {
+			return nil, ErrInvalidProof{Fault: ProofHashMismatch, Cid: k}
+		}
+		if _, ok := proof.blocks[k]; ok {
+			return nil, ErrInvalidProof{Fault: ProofExtraneousBlock, Cid: k}
+		}
+		proof.blocks[k] = blk
+		proof.order = append(proof.order, k)
+	}
+}
+
+func hasCid(cids []cid.Cid, c cid.Cid) bool {
+	for _, k := range cids {
+		if k.Equals(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBlock implements BlockGetter.
+func (p *proofBlocks) GetBlock(_ context.Context, c cid.Cid) (blocks.Block, error) {
+	blk, ok := p.blocks[c]
+	if !ok {
+		if !p.missing.Defined() {
+			p.missing = c
+		}
+		return nil, ErrInvalidProof{Fault: ProofMissingBlock, Cid: c}
+	}
+	p.used.Add(c)
+	return blk, nil
+}
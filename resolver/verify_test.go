@@ -0,0 +1,108 @@
+package resolver_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	merkledag "github.com/ipfs/go-merkledag"
+	dagmock "github.com/ipfs/go-merkledag/test"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/internal/car"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/require"
+)
+
+// writeProof writes the given blocks to a CARv1 stream with the given root.
+func writeProof(t *testing.T, root cid.Cid, blks ...blocks.Block) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	cw, err := car.NewWriter(&buf, []cid.Cid{root})
+	require.NoError(t, err)
+	for _, blk := range blks {
+		require.NoError(t, cw.WriteBlock(blk))
+	}
+	return &buf
+}
+
+func TestVerifyCAR(t *testing.T) {
+	ctx := context.Background()
+	bsrv := dagmock.Bserv()
+
+	a := randNode()
+	b := randNode()
+	c := randNode()
+	other := randNode()
+	require.NoError(t, b.AddNodeLink("grandchild", c))
+	require.NoError(t, a.AddNodeLink("child", b))
+	require.NoError(t, a.AddNodeLink("other", other))
+	for _, n := range []*merkledag.ProtoNode{a, b, c, other} {
+		require.NoError(t, bsrv.AddBlock(ctx, n))
+	}
+
+	p, err := path.FromSegments("/ipfs/", a.Cid().String(), "child", "grandchild")
+	require.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		for _, scope := range []resolver.DagScope{resolver.DagScopeBlock, resolver.DagScopeEntity, resolver.DagScopeAll} {
+			var buf bytes.Buffer
+			require.NoError(t, resolver.WriteCAR(ctx, &buf, bsrv, p, scope))
+			require.NoError(t, resolver.VerifyCAR(ctx, &buf, p, scope, c.Cid()), scope)
+		}
+	})
+
+	testCases := []struct {
+		name  string
+		proof *bytes.Buffer
+		err   error
+	}{
+		{
+			"missing block",
+			writeProof(t, a.Cid(), a, c),
+			resolver.ErrInvalidProof{Fault: resolver.ProofMissingBlock, Cid: b.Cid()},
+		},
+		{
+			"hash mismatch",
+			writeProof(t, a.Cid(), a, mustBlock(t, other.RawData(), b.Cid()), c),
+			resolver.ErrInvalidProof{Fault: resolver.ProofHashMismatch, Cid: b.Cid()},
+		},
+		{
+			"extraneous block",
+			writeProof(t, a.Cid(), a, b, other, c),
+			resolver.ErrInvalidProof{Fault: resolver.ProofExtraneousBlock, Cid: other.Cid()},
+		},
+		{
+			"duplicate block",
+			writeProof(t, a.Cid(), a, b, b, c),
+			resolver.ErrInvalidProof{Fault: resolver.ProofExtraneousBlock, Cid: b.Cid()},
+		},
+		{
+			"wrong root",
+			writeProof(t, b.Cid(), a, b, c),
+			resolver.ErrInvalidProof{Fault: resolver.ProofWrongRoot, Cid: a.Cid()},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := resolver.VerifyCAR(ctx, tc.proof, p, resolver.DagScopeBlock, c.Cid())
+			require.Equal(t, tc.err, err)
+		})
+	}
+
+	t.Run("wrong target", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, resolver.WriteCAR(ctx, &buf, bsrv, p, resolver.DagScopeBlock))
+		err := resolver.VerifyCAR(ctx, &buf, p, resolver.DagScopeBlock, other.Cid())
+		require.Equal(t, resolver.ErrInvalidProof{Fault: resolver.ProofWrongTarget, Cid: c.Cid()}, err)
+		require.EqualError(t, err, "invalid proof: path resolves to "+c.Cid().String())
+	})
+}
+
+func mustBlock(t *testing.T, data []byte, c cid.Cid) blocks.Block {
+	t.Helper()
+	blk, err := blocks.NewBlockWithCid(data, c)
+	require.NoError(t, err)
+	return blk
+}
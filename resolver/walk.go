@@ -0,0 +1,524 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-blockservice"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-fetcher"
+	bsfetcher "github.com/ipfs/go-fetcher/impl/blockservice"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-unixfsnode/data"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/schema"
+	"github.com/multiformats/go-multihash"
+)
+
+// walker resolves path segments one node at a time, loading every block it
+// crosses through a single fetcher session.
+//
+// The session outlives the resolution scope, bounded by the resolver
+// timeouts: nodes it returns may load more blocks lazily, such as the shards
+// of a HAMT directory, until the session ends.
+type walker struct {
+	// session fetches the blocks of the walker. It may be shared with other
+	// walkers.
+	session *fetchSession
+	// endSession ends session if the walker started it with a context of its
+	// own, aborting the fetches left behind by a timeout.
+	endSession context.CancelFunc
+	// scope is the resolution scope, with the timeouts applied to it. It is
+	// ended by cancel.
+	scope        context.Context
+	cancel       context.CancelFunc
+	blockTimeout time.Duration
+	// released is set once the scope has ended: blocks loaded afterwards by
+	// nodes handed to the caller are neither bounded nor accounted.
+	released atomic.Bool
+	// lsys loads blocks from the blockservice session of session, if it
+	// has one, bounding and accounting every block read, including those an
+	// ADL loads internally.
+	lsys   *ipld.LinkSystem
+	budget *budgetTracker
+	// skipped holds the segments of the path resolved before the walker
+	// started, such as those found in the path index.
+	skipped []string
+	// segment and index locate the path segment being resolved, for budget
+	// errors. index counts the skipped segments, and is -1 at the root of a
+	// path.
+	segment string
+	index   int
+	// ns is the namespace of the paths resolved, if known.
+	ns string
+	// reifier is the NodeReifier for the namespace of the paths resolved.
+	reifier ipld.NodeReifier
+	// noLinkNames and noLinkShardBlocks configure the details of ErrNoLink
+	// errors (see WithNoLinkDetails).
+	noLinkNames       int
+	noLinkShardBlocks int
+	// rootPrototype, if set, is the prototype of the root block of the path
+	// instead of the one chosen from its link.
+	rootPrototype ipld.NodePrototype
+	// onStep, if set, is called with the root and each segment as soon as
+	// they are resolved.
+	onStep func(ResolvedSegment) error
+}
+
+// close ends the resolution scope, and the session if the walker started it
+// with a context of its own.
+func (w *walker) close() {
+	w.released.Store(true)
+	w.cancel()
+	w.endSession()
+}
+
+// fetchSession is the session walkers fetch blocks through, started on
+// first use. With a FetcherFactory, it is a blockservice session, read by
+// the link systems of the walkers. Other factories are used through fetcher
+// sessions: one per namespace if the factory can change its NodeReifier,
+// so that nodes are reified for the namespace of each path, or else a
+// single one. A session may also be a fetcher session of the caller.
+type fetchSession struct {
+	// factory is nil for a fetcher session of the caller.
+	factory fetcher.Factory
+	ctx     context.Context
+	// source is factory, if it is a FetcherFactory.
+	source *FetcherFactory
+
+	mu       sync.Mutex
+	blocks   *blockservice.Session
+	fetchers map[string]fetcher.Fetcher
+}
+
+// newFetchSession prepares a session of factory, started with ctx.
+func newFetchSession(ctx context.Context, factory fetcher.Factory) *fetchSession {
+	s := &fetchSession{factory: factory, ctx: ctx}
+	if ff, ok := factory.(FetcherFactory); ok {
+		s.source = &ff
+	}
+	return s
+}
+
+// newFetcherSession returns a session fetching blocks through f, a fetcher
+// session of the caller, for paths in every namespace.
+func newFetcherSession(f fetcher.Fetcher) *fetchSession {
+	// the session of the caller was started with a context of its own
+	return &fetchSession{ctx: context.Background(), fetchers: map[string]fetcher.Fetcher{"": f}}
+}
+
+// getBlock fetches the block c through the blockservice session of the
+// FetcherFactory, starting it if needed.
+func (s *fetchSession) getBlock(c cid.Cid) (blocks.Block, error) {
+	s.mu.Lock()
+	if s.blocks == nil {
+		s.blocks = blockservice.NewSession(s.ctx, s.source.blockService)
+	}
+	bs := s.blocks
+	s.mu.Unlock()
+	return bs.GetBlock(s.ctx, c)
+}
+
+// fetcher returns the fetcher session for paths in the namespace ns, whose
+// nodes are reified with reifier, starting it if needed.
+func (s *fetchSession) fetcher(ns string, reifier ipld.NodeReifier) fetcher.Fetcher {
+	rf, ok := s.factory.(reifierFactory)
+	if !ok {
+		ns = ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.fetchers[ns]; ok {
+		return f
+	}
+	factory := s.factory
+	if ns != "" {
+		factory = rf.WithReifier(reifier)
+	}
+	if s.fetchers == nil {
+		s.fetchers = make(map[string]fetcher.Fetcher)
+	}
+	f := factory.NewSession(s.ctx)
+	s.fetchers[ns] = f
+	return f
+}
+
+// load fetches the block referenced by lnk. lnkNode is the node lnk was read
+// from, if any, and is used to pick the prototype of the loaded node.
+func (w *walker) load(ctx context.Context, lnk cidlink.Link, lnkNode ipld.Node) (ipld.Node, error) {
+	np, err := w.prototype(lnk, lnkNode)
+	if err != nil {
+		return nil, fmt.Errorf("could not load link %q: %w", lnk, err)
+	}
+	return w.loadAs(ctx, lnk, np)
+}
+
+// loadAs fetches the block referenced by lnk as a node of the prototype np.
+//
+// Blocks inlined in identity cids are decoded without fetching anything.
+func (w *walker) loadAs(ctx context.Context, lnk cidlink.Link, np ipld.NodePrototype) (ipld.Node, error) {
+	if isInline(lnk.Cid) {
+		return w.loadInline(ctx, lnk, np)
+	}
+	if w.lsys != nil {
+		return w.lsys.Load(ipld.LinkContext{Ctx: w.session.ctx}, lnk, np)
+	}
+	return w.fetch(ctx, lnk, np)
+}
+
+// newLinkSystem returns a link system loading blocks from the blockservice
+// session of w, reifying nodes for the namespace of w.
+func (w *walker) newLinkSystem() *ipld.LinkSystem {
+	lsys := cidlink.DefaultLinkSystem()
+	// blocks are verified by the blockservice
+	lsys.TrustedStorage = true
+	lsys.StorageReadOpener = w.openBlock
+	lsys.NodeReifier = w.reifier
+	return &lsys
+}
+
+// openBlock reads the block lnk from the blockservice session of w. Blocks
+// read during the resolution are bounded and accounted.
+func (w *walker) openBlock(_ ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+	clnk, ok := lnk.(cidlink.Link)
+	if !ok {
+		return nil, fmt.Errorf("invalid link type for loading: %v", lnk)
+	}
+	if w.released.Load() {
+		blk, err := w.session.getBlock(clnk.Cid)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(blk.RawData()), nil
+	}
+
+	if err := w.budget.fetchBlock(w.segment, w.index); err != nil {
+		return nil, err
+	}
+	v, err := w.bounded(lnk, func() (interface{}, error) {
+		return w.session.getBlock(clnk.Cid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	data := v.(blocks.Block).RawData()
+	if err := w.budget.fetchedBytes(len(data), w.segment, w.index); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// fetch loads lnk through a fetcher session, for sessions other than those
+// of a FetcherFactory. The blocks an ADL loads internally are not accounted, and
+// neither are block sizes.
+func (w *walker) fetch(ctx context.Context, lnk cidlink.Link, np ipld.NodePrototype) (ipld.Node, error) {
+	if w.budget.MaxBytes > 0 {
+		return nil, fmt.Errorf("cannot enforce a %s budget without a FetcherFactory", BudgetBytes)
+	}
+	if err := w.budget.fetchBlock(w.segment, w.index); err != nil {
+		return nil, err
+	}
+	session := w.session.fetcher(w.ns, w.reifier)
+	v, err := w.bounded(lnk, func() (interface{}, error) {
+		return session.BlockOfType(ctx, lnk, np)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(ipld.Node), nil
+}
+
+// bounded runs fetch, fetching the block lnk, and returns its result, giving
+// up when the resolution scope ends or when the block timeout elapses.
+// Sessions may ignore the context passed to each call, so a fetch given up
+// on is left to the session, which is ended if the walker started it.
+func (w *walker) bounded(lnk ipld.Link, fetch func() (interface{}, error)) (interface{}, error) {
+	if w.blockTimeout <= 0 && w.scope.Done() == nil {
+		return fetch()
+	}
+
+	var timeout <-chan time.Time
+	if w.blockTimeout > 0 {
+		timer := time.NewTimer(w.blockTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	type result struct {
+		v   interface{}
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := fetch()
+		done <- result{v, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.v, res.err
+	case <-timeout:
+		w.endSession()
+		return nil, fmt.Errorf("block %s not fetched within %s: %w", lnk, w.blockTimeout, context.DeadlineExceeded)
+	case <-w.scope.Done():
+		w.endSession()
+		return nil, w.scope.Err()
+	}
+}
+
+// loadInline decodes the block inlined in the identity cid of lnk as a node
+// of the prototype np, reified as the session would.
+func (w *walker) loadInline(ctx context.Context, lnk cidlink.Link, np ipld.NodePrototype) (ipld.Node, error) {
+	dmh, err := multihash.Decode(lnk.Cid.Hash())
+	if err != nil {
+		return nil, err
+	}
+	lsys := cidlink.DefaultLinkSystem()
+	lsys.StorageReadOpener = func(ipld.LinkContext, ipld.Link) (io.Reader, error) {
+		return bytes.NewReader(dmh.Digest), nil
+	}
+	lsys.NodeReifier = w.reifier
+	nd, err := lsys.Load(ipld.LinkContext{Ctx: ctx}, lnk, np)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode inline block %s: %w", lnk.Cid, err)
+	}
+	return nd, nil
+}
+
+// inlinePrototypeChooser picks the prototype of blocks inlined in identity
+// cids.
+var inlinePrototypeChooser = dagpb.AddSupportToChooser(bsfetcher.DefaultPrototypeChooser)
+
+// isInline returns true if the block of c is inlined in c, that is if c is
+// an identity cid.
+func isInline(c cid.Cid) bool {
+	return c.Defined() && c.Prefix().MhType == multihash.IDENTITY
+}
+
+// reached reports the resolution of a segment to onStep, unless ctx is done.
+func (w *walker) reached(ctx context.Context, seg ResolvedSegment) error {
+	if w.onStep == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return w.onStep(seg)
+}
+
+func (w *walker) prototype(lnk ipld.Link, lnkNode ipld.Node) (ipld.NodePrototype, error) {
+	if tlnkNd, ok := lnkNode.(schema.TypedLinkNode); ok {
+		return tlnkNd.LinkTargetNodePrototype(), nil
+	}
+	if clnk, ok := lnk.(cidlink.Link); ok && isInline(clnk.Cid) {
+		return inlinePrototypeChooser(lnk, ipld.LinkContext{})
+	}
+	if w.session.source != nil {
+		return w.session.source.PrototypeChooser(lnk, ipld.LinkContext{})
+	}
+	return w.session.fetcher(w.ns, w.reifier).PrototypeFromLink(lnk)
+}
+
+// atRoot locates the walker at the root of the resolution: the root of the
+// path, or the last segment skipped.
+func (w *walker) atRoot() {
+	if n := len(w.skipped); n > 0 {
+		w.segment, w.index = w.skipped[n-1], n-1
+		return
+	}
+	w.segment, w.index = "", -1
+}
+
+// checkDepth verifies that resolving segments after those skipped stays
+// within the depth limit.
+func (w *walker) checkDepth(segments []string) error {
+	return w.budget.checkDepth(append(append([]string{}, w.skipped...), segments...))
+}
+
+// walk follows segments starting from the already resolved from. It returns
+// the resolution of each segment. If loadLast is false, a link reached by the
+// final segment is returned as is rather than loaded.
+//
+// On error, the segments resolved before the failing one are returned.
+func (w *walker) walk(ctx context.Context, from ResolvedSegment, segments []string, loadLast bool) ([]ResolvedSegment, error) {
+	if err := w.checkDepth(segments); err != nil {
+		return nil, err
+	}
+
+	resolved := make([]ResolvedSegment, 0, len(segments))
+	cur := from
+	for i, seg := range segments {
+		w.segment, w.index = seg, len(w.skipped)+i
+		if what := notTraversable(cur, w.ns); what != "" {
+			return resolved, ErrNotTraversable{Prefix: w.prefix(from, segments[:i]), Segment: seg, Kind: cur.Node.Kind(), Block: cur.Block, What: what}
+		}
+
+		ps, next, err := lookup(cur.Node, seg)
+		switch err.(type) {
+		case nil:
+		case ipld.ErrNotExists, schema.ErrNoSuchField:
+			return resolved, w.noLink(ctx, from, segments[:i], cur, seg)
+		default:
+			if missing, ok := w.missingBlock(err, from, segments, i); ok {
+				return resolved, missing
+			}
+			return resolved, err
+		}
+
+		step := ResolvedSegment{Name: seg, Segment: ps, Node: next, Block: cur.Block, Depth: cur.Depth + 1}
+		if next.Kind() == ipld.Kind_Link && (loadLast || i < len(segments)-1) {
+			lnk, err := next.AsLink()
+			if err != nil {
+				return resolved, err
+			}
+			clnk, ok := lnk.(cidlink.Link)
+			if !ok {
+				var nextSeg string
+				if i < len(segments)-1 {
+					nextSeg = segments[i+1]
+				}
+				return resolved, ErrNotTraversable{Prefix: w.prefix(from, segments[:i+1]), Segment: nextSeg, Kind: ipld.Kind_Link, Block: cur.Block, What: "non-CID link"}
+			}
+			step.Node, err = w.load(ctx, clnk, next)
+			switch err.(type) {
+			case nil:
+			case ErrBudgetExceeded:
+				return resolved, err
+			default:
+				if missing, ok := w.missingBlock(err, from, segments, i); ok {
+					return resolved, missing
+				}
+				return resolved, fmt.Errorf("error traversing node at %q: %w", strings.Join(segments[:i+1], "/"), err)
+			}
+			step.Block, step.Depth, step.Boundary, step.Inline = clnk.Cid, 0, true, isInline(clnk.Cid)
+		}
+
+		if err := w.budget.visit(w.segment, w.index); err != nil {
+			return resolved, err
+		}
+		resolved = append(resolved, step)
+		if err := w.reached(ctx, step); err != nil {
+			return resolved, err
+		}
+		cur = step
+	}
+	return resolved, nil
+}
+
+// lookup resolves the segment seg of the map or list node nd. On lists, seg
+// must be a list index written in canonical decimal form; on maps, it is a
+// key, even when numeric. The returned segment is typed accordingly.
+func lookup(nd ipld.Node, seg string) (ipld.PathSegment, ipld.Node, error) {
+	switch nd.Kind() {
+	case ipld.Kind_Map:
+		next, err := nd.LookupByString(seg)
+		return ipld.PathSegmentOfString(seg), next, err
+	case ipld.Kind_List:
+		idx, err := strconv.ParseInt(seg, 10, 64)
+		if err != nil || idx < 0 || strconv.FormatInt(idx, 10) != seg {
+			return ipld.PathSegment{}, nil, ipld.ErrNotExists{Segment: ipld.PathSegmentOfString(seg)}
+		}
+		next, err := nd.LookupByIndex(idx)
+		return ipld.PathSegmentOfInt(idx), next, err
+	default:
+		return ipld.PathSegment{}, nil, ipld.ErrNotExists{Segment: ipld.PathSegmentOfString(seg)}
+	}
+}
+
+// ErrNotTraversable is returned when a path segment is applied to a node that
+// cannot have children, such as a raw block, a UnixFS file, a scalar value or
+// a link that is not a CID link.
+type ErrNotTraversable struct {
+	// Prefix is the path to the node. It is empty when the resolution did
+	// not start from the root of a path.
+	Prefix path.Path
+	// Segment is the segment that could not be applied.
+	Segment string
+	// Kind is the data model kind of the node.
+	Kind ipld.Kind
+	// Block is the cid of the block containing the node. Its codec is the
+	// codec the node was decoded with.
+	Block cid.Cid
+	// What describes the node, e.g. "raw block" or "string value".
+	What string
+}
+
+// Error implements the Error interface for ErrNotTraversable with a useful
+// human readable message.
+func (e ErrNotTraversable) Error() string {
+	at := string(e.Prefix)
+	if at == "" {
+		at = "a node of " + e.Block.String()
+	}
+	return fmt.Sprintf("cannot traverse into %s at %s (segment %q)", e.What, at, e.Segment)
+}
+
+// notTraversable describes the node reached by seg if it cannot have
+// children when resolving paths in the namespace ns, or returns "".
+func notTraversable(seg ResolvedSegment, ns string) string {
+	var codec uint64
+	if seg.Block.Defined() {
+		codec = seg.Block.Prefix().Codec
+	}
+	switch seg.Node.Kind() {
+	case ipld.Kind_Map, ipld.Kind_List:
+		if shard, ok := seg.Node.(pbShard); ok && ns != nsIPLD {
+			switch unixfsType(shard) {
+			case data.Data_File, data.Data_Raw:
+				return "UnixFS file"
+			case data.Data_Symlink:
+				return "UnixFS symlink"
+			}
+		}
+		return ""
+	case ipld.Kind_Bytes:
+		if codec == cid.Raw && seg.Depth == 0 {
+			return "raw block"
+		}
+	}
+	what := strings.ToLower(seg.Node.Kind().String()) + " value"
+	if seg.Block.Defined() {
+		what += " in " + codecName(codec) + " block"
+	}
+	return what
+}
+
+// codecName returns the multicodec name of codec.
+func codecName(codec uint64) string {
+	switch codec {
+	case cid.DagProtobuf:
+		return "dag-pb"
+	case cid.DagCBOR:
+		return "dag-cbor"
+	case 0x0129:
+		return "dag-json"
+	}
+	if name, ok := cid.CodecToStr[codec]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", codec)
+}
+
+// unixfsType returns the UnixFS type of the dag-pb node nd, or -1 if it is not
+// UnixFS.
+func unixfsType(nd pbShard) int64 {
+	if !nd.FieldData().Exists() {
+		return -1
+	}
+	ufsData, err := data.DecodeUnixFSData(nd.FieldData().Must().Bytes())
+	if err != nil {
+		return -1
+	}
+	return ufsData.FieldDataType().Int()
+}
@@ -0,0 +1,97 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	bsfetcher "github.com/ipfs/go-fetcher/impl/blockservice"
+	merkledag "github.com/ipfs/go-merkledag"
+	dagmock "github.com/ipfs/go-merkledag/test"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/ipfs/go-unixfsnode"
+	"github.com/ipfs/go-unixfsnode/data"
+	"github.com/ipfs/go-unixfsnode/data/builder"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrNotTraversable(t *testing.T) {
+	ctx := context.Background()
+
+	rawData := []byte("raw data")
+	mh, err := multihash.Sum(rawData, multihash.SHA2_256, -1)
+	require.NoError(t, err)
+	raw, err := blocks.NewBlockWithCid(rawData, cid.NewCidV1(cid.Raw, mh))
+	require.NoError(t, err)
+	doc := cborBlock(t, `{"name":"doc","raw":{"/":"`+raw.Cid().String()+`"}}`)
+
+	ufs, err := builder.BuildUnixFS(func(b *builder.Builder) {
+		builder.DataType(b, data.Data_File)
+		builder.Data(b, []byte("file data"))
+	})
+	require.NoError(t, err)
+	file := new(merkledag.ProtoNode)
+	file.SetData(data.EncodeUnixFSData(ufs))
+	dir := randNode()
+	require.NoError(t, dir.AddNodeLink("file", file))
+
+	bsrv := dagmock.Bserv()
+	for _, blk := range []blocks.Block{raw, doc, file, dir} {
+		require.NoError(t, bsrv.AddBlock(ctx, blk))
+	}
+	factory := bsfetcher.NewFetcherConfig(bsrv)
+	factory.PrototypeChooser = dagpb.AddSupportToChooser(bsfetcher.DefaultPrototypeChooser)
+	factory.NodeReifier = unixfsnode.Reify
+	r := resolver.NewBasicResolver(factory)
+
+	cases := []struct {
+		path    string
+		prefix  string
+		segment string
+		kind    ipld.Kind
+		block   cid.Cid
+		what    string
+	}{
+		{"/ipfs/" + raw.Cid().String() + "/a", "/ipfs/" + raw.Cid().String(), "a", ipld.Kind_Bytes, raw.Cid(), "raw block"},
+		{"/ipld/" + doc.Cid().String() + "/raw/a/b", "/ipld/" + doc.Cid().String() + "/raw", "a", ipld.Kind_Bytes, raw.Cid(), "raw block"},
+		{"/ipld/" + doc.Cid().String() + "/name/a", "/ipld/" + doc.Cid().String() + "/name", "a", ipld.Kind_String, doc.Cid(), "string value in dag-cbor block"},
+		{"/ipfs/" + dir.Cid().String() + "/file/a", "/ipfs/" + dir.Cid().String() + "/file", "a", ipld.Kind_Map, file.Cid(), "UnixFS file"},
+	}
+	for _, c := range cases {
+		_, _, err := r.ResolveToLastNode(ctx, path.FromString(c.path))
+		var nerr resolver.ErrNotTraversable
+		require.ErrorAs(t, err, &nerr, c.path)
+		require.Equal(t, resolver.ErrNotTraversable{
+			Prefix:  path.FromString(c.prefix),
+			Segment: c.segment,
+			Kind:    c.kind,
+			Block:   c.block,
+			What:    c.what,
+		}, nerr)
+	}
+
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString("/ipfs/"+raw.Cid().String()+"/a"))
+	require.EqualError(t, err, `cannot traverse into raw block at /ipfs/`+raw.Cid().String()+` (segment "a")`)
+
+	// paths into a file are errors, even for the methods returning the
+	// nodes found before a missing entry
+	filePath := path.FromString("/ipfs/" + dir.Cid().String() + "/file/a")
+	_, _, err = r.ResolvePath(ctx, filePath)
+	require.ErrorAs(t, err, &resolver.ErrNotTraversable{})
+	_, err = r.ResolvePathComponents(ctx, filePath)
+	require.ErrorAs(t, err, &resolver.ErrNotTraversable{})
+
+	// files are traversable with the data model
+	_, rest, err := r.ResolveToLastNode(ctx, path.FromString("/ipld/"+file.Cid().String()+"/Data"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"Data"}, rest)
+
+	// a missing entry is not a traversal error
+	_, _, err = r.ResolveToLastNode(ctx, path.FromString("/ipfs/"+dir.Cid().String()+"/missing"))
+	require.ErrorAs(t, err, &resolver.ErrNoLink{})
+}
@@ -0,0 +1,221 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	path "github.com/ipfs/go-path"
+)
+
+// watchMaxBackoff bounds the delay between polls after repeated failures, as
+// a multiple of the watch interval.
+const watchMaxBackoff = 32
+
+// Clock tells the time to a resolver. It lets tests control the expiry of
+// cached names and the schedule of Watch polls.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel receiving the current time once d elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the system clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// WatchState is the resolution of a watched path, as returned by
+// ResolveToLastNode.
+type WatchState struct {
+	// Cid is the cid of the last block referenced by the path.
+	Cid cid.Cid
+	// Remainder is the list of path segments to traverse from the last block
+	// boundary to the final node within its block.
+	Remainder []string
+	// Names holds the names resolved, in order, to find the root of the path.
+	Names []NameHop
+
+	// segments holds the cid the root and each watched segment resolved to.
+	segments []cid.Cid
+}
+
+// WatchEvent reports a change in the resolution of a watched path.
+type WatchEvent struct {
+	// Old is the previous resolution of the path. It is zero for the first
+	// event of a watch.
+	Old WatchState
+	// New is the current resolution of the path.
+	New WatchState
+	// Segment is the first segment after the root of the watched path whose
+	// resolution changed, even if the root changed too. It is empty when the
+	// path has no segments after its root.
+	Segment string
+}
+
+// Watch resolves fpath with r every interval and sends an event on the
+// returned channel whenever the cid or the remainder it resolves to changes,
+// the first resolution included. The channel is closed when ctx is done.
+//
+// Polls are delayed until the names at the root of the path expire, when
+// their TTL is longer than interval. After a failed resolution, the delay
+// doubles up to 32 times interval. A path without names never changes, so it
+// is resolved until it succeeds once.
+//
+// r must have a method Watch(ctx, fpath, interval), as the resolvers of this
+// package do.
+func Watch(ctx context.Context, r Resolver, fpath path.Path, interval time.Duration) (<-chan WatchEvent, error) {
+	wr, ok := r.(interface {
+		Watch(context.Context, path.Path, time.Duration) (<-chan WatchEvent, error)
+	})
+	if !ok {
+		return nil, ErrUnsupported{Resolver: r, Method: "Watch"}
+	}
+	return wr.Watch(ctx, fpath, interval)
+}
+
+// Watch watches the resolution of fpath (see the Watch function).
+func (r *basicResolver) Watch(ctx context.Context, fpath path.Path, interval time.Duration) (<-chan WatchEvent, error) {
+	// validate path
+	if err := fpath.IsValid(); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		return nil, errors.New("watch interval must be positive")
+	}
+
+	// the segments written after the root in fpath
+	watched := fpath.Segments()
+	if len(watched) > 0 && (watched[0] == "ipfs" || watched[0] == "ipld" || watched[0] == "ipns") {
+		watched = watched[1:]
+	}
+	if len(watched) > 0 {
+		watched = watched[1:]
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+
+		var last WatchState
+		failures := 0
+		for {
+			delay := interval
+			st, err := r.watchState(ctx, fpath, len(watched))
+			switch {
+			case err != nil:
+				log.Debugf("could not resolve watched path %s: %s", fpath, err)
+				if failures < 5 {
+					failures++
+				}
+				delay = interval << failures
+				if delay > watchMaxBackoff*interval {
+					delay = watchMaxBackoff * interval
+				}
+			default:
+				failures = 0
+				if changed, segment := watchChange(last, st, watched); changed {
+					select {
+					case events <- WatchEvent{Old: last, New: st, Segment: segment}:
+					case <-ctx.Done():
+						return
+					}
+				}
+				last = st
+				if len(st.Names) == 0 {
+					// immutable paths never change
+					<-ctx.Done()
+					return
+				}
+				if ttl := minTTL(st.Names); ttl > delay {
+					delay = ttl
+				}
+			}
+
+			select {
+			case <-r.opts.clock.After(delay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// watchState resolves fpath, whose last watched segments are watched.
+func (r *basicResolver) watchState(ctx context.Context, fpath path.Path, watched int) (WatchState, error) {
+	ctx, w := r.newWalker(ctx, "")
+	defer w.close()
+	c, p, hops, err := r.resolveRoot(ctx, w, fpath)
+	if err != nil {
+		return WatchState{}, err
+	}
+	st := WatchState{Cid: c, Names: hops, segments: []cid.Cid{c}}
+	if len(p) == 0 {
+		return st, nil
+	}
+
+	// resolve all segments, without loading a link found under the last one
+	res, err := r.resolve(ctx, w, c, p, false)
+	if err != nil {
+		return WatchState{}, err
+	}
+	for i := len(p) - watched; i < len(p); i++ {
+		sc, _, err := lastNode(fpath, res.Segments[i], remainder(res.Segments[:i+1]))
+		if err != nil {
+			return WatchState{}, err
+		}
+		st.segments = append(st.segments, sc)
+	}
+	st.Cid, st.Remainder, err = lastNode(fpath, res.Last(), res.Remainder)
+	if err != nil {
+		return WatchState{}, err
+	}
+	return st, nil
+}
+
+// watchChange reports whether the resolution of a watched path changed from
+// old to st, and the first of the watched segments whose resolution changed.
+func watchChange(old, st WatchState, watched []string) (bool, string) {
+	if old.Cid.Equals(st.Cid) && equalSegments(old.Remainder, st.Remainder) {
+		return false, ""
+	}
+	// the root may change while some of the segments under it still
+	// resolve to the same blocks: report the first one that did not
+	for i := 1; i < len(st.segments); i++ {
+		if i >= len(old.segments) || !old.segments[i].Equals(st.segments[i]) {
+			return true, watched[i-1]
+		}
+	}
+	// only the remainder within the last block changed
+	if len(watched) == 0 {
+		return true, ""
+	}
+	return true, watched[len(watched)-1]
+}
+
+func equalSegments(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func minTTL(hops []NameHop) time.Duration {
+	var ttl time.Duration
+	for i, hop := range hops {
+		if i == 0 || hop.TTL < ttl {
+			ttl = hop.TTL
+		}
+	}
+	return ttl
+}
@@ -0,0 +1,126 @@
+package resolver_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	merkledag "github.com/ipfs/go-merkledag"
+	path "github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a Clock whose time only moves when advanced. Every call to
+// After reports the requested delay on waits.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	waits   chan time.Duration
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0), waits: make(chan time.Duration, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeWaiter{deadline: c.now.Add(d), ch: ch})
+	c.mu.Unlock()
+	c.waits <- d
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg1 := randNode()
+	cfg2 := randNode()
+	site := func(cfg *merkledag.ProtoNode) *merkledag.ProtoNode {
+		n := randNode()
+		require.NoError(t, n.AddNodeLink("config.json", cfg))
+		return n
+	}
+	v1, v2, v3 := site(cfg1), site(cfg1), site(cfg2)
+	missing := randNode()
+
+	clock := newFakeClock()
+	names := resolver.NewMemoryNameResolver()
+	names.Publish("site", path.FromCid(v1.Cid()), 0)
+	r := resolver.NewBasicResolver(unixfsFetcherFactory(t, cfg1, cfg2, v1, v2, v3), resolver.WithNameResolver(names), resolver.WithClock(clock))
+
+	events, err := resolver.Watch(ctx, r, path.FromString("/ipns/site/config.json"), time.Minute)
+	require.NoError(t, err)
+
+	// the first resolution is reported
+	ev := <-events
+	require.False(t, ev.Old.Cid.Defined())
+	require.Equal(t, cfg1.Cid(), ev.New.Cid)
+	require.Equal(t, "config.json", ev.Segment)
+	require.Equal(t, time.Minute, <-clock.waits)
+
+	// a new root with the same config is not reported
+	names.Publish("site", path.FromCid(v2.Cid()), 0)
+	clock.Advance(time.Minute)
+	require.Equal(t, time.Minute, <-clock.waits)
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event: %v", ev)
+	default:
+	}
+
+	// failures back off
+	names.Publish("site", path.FromCid(missing.Cid()), 0)
+	clock.Advance(time.Minute)
+	require.Equal(t, 2*time.Minute, <-clock.waits)
+	clock.Advance(2 * time.Minute)
+	require.Equal(t, 4*time.Minute, <-clock.waits)
+
+	// a new config is reported, and polls wait for the TTL of the name
+	names.Publish("site", path.FromCid(v3.Cid()), 10*time.Minute)
+	clock.Advance(4 * time.Minute)
+	ev = <-events
+	require.Equal(t, cfg1.Cid(), ev.Old.Cid)
+	require.Equal(t, cfg2.Cid(), ev.New.Cid)
+	// the root changed too, but the first segment that differs is reported
+	require.Equal(t, "config.json", ev.Segment)
+	require.Len(t, ev.New.Names, 1)
+	require.Equal(t, 10*time.Minute, <-clock.waits)
+
+	_, err = resolver.Watch(ctx, &recordingResolver{Resolver: r}, path.FromString("/ipns/site"), time.Minute)
+	require.ErrorAs(t, err, &resolver.ErrUnsupported{})
+
+	cancel()
+	_, ok := <-events
+	require.False(t, ok)
+}